@@ -75,9 +75,9 @@ func (nodes groupedCheckedNodes[T]) Alive() []Node[T] {
 
 type checkExecutorFunc[T any] func(ctx context.Context, node Node[T]) (bool, time.Duration, error)
 
-// checkNodes takes slice of nodes, checks them in parallel and returns the alive ones.
+// checkNodes takes slice of nodes, checks them in parallel and returns the alive ones with their check latencies.
 // Accepts customizable executor which enables time-independent tests for node sorting based on 'latency'.
-func checkNodes[T any](ctx context.Context, nodes []Node[T], executor checkExecutorFunc[T], tracer Tracer[T], errCollector *errorsCollector) AliveNodes[T] {
+func checkNodes[T any](ctx context.Context, nodes []Node[T], executor checkExecutorFunc[T], tracer Tracer[T], errCollector *errorsCollector) (AliveNodes[T], []time.Duration) {
 	checkedNodes := groupedCheckedNodes[T]{
 		Primaries: make(checkedNodesList[T], 0, len(nodes)),
 		Standbys:  make(checkedNodesList[T], 0, len(nodes)),
@@ -124,11 +124,18 @@ func checkNodes[T any](ctx context.Context, nodes []Node[T], executor checkExecu
 	sort.Sort(checkedNodes.Primaries)
 	sort.Sort(checkedNodes.Standbys)
 
+	latencies := make([]time.Duration, 0, len(checkedNodes.Primaries)+len(checkedNodes.Standbys))
+	for _, list := range []checkedNodesList[T]{checkedNodes.Primaries, checkedNodes.Standbys} {
+		for _, node := range list {
+			latencies = append(latencies, node.Latency)
+		}
+	}
+
 	return AliveNodes[T]{
 		Alive:     checkedNodes.Alive(),
 		Primaries: checkedNodes.Primaries.Nodes(),
 		Standbys:  checkedNodes.Standbys.Nodes(),
-	}
+	}, latencies
 }
 
 // checkExecutor returns checkExecutorFunc which can execute supplied check.
@@ -27,6 +27,35 @@ type AliveNodes[T any] struct {
 	Standbys  []Node[T]
 }
 
+func (nodes AliveNodes[T]) except(exclude []Node[T]) AliveNodes[T] {
+	if len(exclude) == 0 {
+		return nodes
+	}
+
+	return AliveNodes[T]{
+		Alive:     exceptNodes(nodes.Alive, exclude),
+		Primaries: exceptNodes(nodes.Primaries, exclude),
+		Standbys:  exceptNodes(nodes.Standbys, exclude),
+	}
+}
+
+func exceptNodes[T any](nodes []Node[T], exclude []Node[T]) []Node[T] {
+	res := make([]Node[T], 0, len(nodes))
+
+outer:
+	for _, node := range nodes {
+		for _, ex := range exclude {
+			if node == ex {
+				continue outer
+			}
+		}
+
+		res = append(res, node)
+	}
+
+	return res
+}
+
 // Cluster consists of number of 'nodes' of a single SQL database.
 // Background goroutine periodically checks nodes and updates their status.
 type Cluster[T any] struct {
@@ -42,6 +71,7 @@ type Cluster[T any] struct {
 	// Status
 	updateStopper chan struct{}
 	aliveNodes    atomic.Value
+	latencies     latencyWindow
 	nodes         []Node[T]
 	errCollector  errorsCollector
 
@@ -111,6 +141,28 @@ func (cl *Cluster[T]) Nodes() []Node[T] {
 	return cl.nodes
 }
 
+// AliveNodes returns nodes, which were considered alive during last update.
+func (cl *Cluster[T]) AliveNodes() AliveNodes[T] {
+	return cl.nodesAlive()
+}
+
+// Latency returns percentile (from 0 to 1) of latencies of latest checks of alive nodes.
+// ok is false, if no node is checked alive yet.
+func (cl *Cluster[T]) Latency(percentile float64) (d time.Duration, ok bool) {
+	return cl.latencies.percentile(percentile)
+}
+
+// IsPrimary reports whether node was considered alive primary during last update.
+func (cl *Cluster[T]) IsPrimary(node Node[T]) bool {
+	for _, primary := range cl.nodesAlive().Primaries {
+		if primary.Addr() == node.Addr() {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (cl *Cluster[T]) nodesAlive() AliveNodes[T] {
 	return cl.aliveNodes.Load().(AliveNodes[T])
 }
@@ -261,6 +313,11 @@ func (cl *Cluster[T]) Node(criteria NodeStateCriteria) Node[T] {
 	return cl.node(cl.nodesAlive(), criteria)
 }
 
+// NodeExcept returns cluster node with specified status, that is not one of excluded nodes.
+func (cl *Cluster[T]) NodeExcept(criteria NodeStateCriteria, exclude ...Node[T]) Node[T] {
+	return cl.node(cl.nodesAlive().except(exclude), criteria)
+}
+
 func (cl *Cluster[T]) node(nodes AliveNodes[T], criteria NodeStateCriteria) Node[T] {
 	switch criteria {
 	case Alive:
@@ -311,8 +368,9 @@ func (cl *Cluster[T]) updateNodes() {
 	ctx, cancel := context.WithTimeout(context.Background(), cl.updateTimeout)
 	defer cancel()
 
-	alive := checkNodes(ctx, cl.nodes, checkExecutor(cl.checker), cl.tracer, &cl.errCollector)
+	alive, latencies := checkNodes(ctx, cl.nodes, checkExecutor(cl.checker), cl.tracer, &cl.errCollector)
 	cl.aliveNodes.Store(alive)
+	cl.latencies.add(latencies)
 
 	if cl.tracer.UpdatedNodes != nil {
 		cl.tracer.UpdatedNodes(alive)
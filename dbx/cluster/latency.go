@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const latencyWindowSize = 1000
+
+// latencyWindow keeps latencies of latest node checks.
+type latencyWindow struct {
+	mu      sync.RWMutex
+	samples []time.Duration
+	next    int
+	sorted  []time.Duration // This is recalculated after every update
+}
+
+func (w *latencyWindow) add(latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, d := range latencies {
+		if len(w.samples) < latencyWindowSize {
+			w.samples = append(w.samples, d)
+			continue
+		}
+
+		w.samples[w.next] = d
+		w.next = (w.next + 1) % latencyWindowSize
+	}
+
+	w.sorted = append(w.sorted[:0], w.samples...)
+	sort.Slice(w.sorted, func(i, j int) bool {
+		return w.sorted[i] < w.sorted[j]
+	})
+}
+
+func (w *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if len(w.sorted) == 0 {
+		return 0, false
+	}
+
+	return w.sorted[int(float64(len(w.sorted)-1)*p)], true
+}
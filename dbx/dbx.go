@@ -26,6 +26,8 @@ type DBxer[TConn any, TTx any, TTxOptions any] interface {
 		f func(ctx context.Context, db DBxer[TConn, TTx, TTxOptions]) error,
 		opts TTxOptions) error
 	Tx() (TTx, error)
+	OnCommit(f func(ctx context.Context))
+	OnRollback(f func(ctx context.Context, err error))
 	GetConn(ctx context.Context, strategy GetNodeStragegy) (TConn, error)
 	GetWriteToConn(ctx context.Context) (TConn, error)
 	GetReadFromConn(ctx context.Context) (TConn, error)
@@ -45,6 +47,19 @@ type DB[T any] struct {
 	ReadFromNodeStrategy GetNodeStragegy // This is used, when we can clearly guess, that query is a read query (for example, Query())
 	DefaultNodeStrategy  GetNodeStragegy // This is used, when we can not figure out, what type of request is formed (like Prepare())
 
+	ReadRetryPolicy RetryPolicy   // This is used to retry failed reads on another node
+	TxRetryPolicy   TxRetryPolicy // This is used to retry whole transactions in RetryTx
+
+	TxHooks *TxHooks // This is set, when DB is bound to transaction
+
+	StructScanMode StructScanMode // This is used by struct scanning helpers
+
+	TenantScope *TenantScope // This is applied to connections and transactions, if set
+
+	Hedger *Hedger // This is used to hedge reads, if set
+
+	Tracer Tracer[T] // This is used to trace retries and hedges
+
 	Ctx context.Context
 }
 
@@ -91,6 +106,13 @@ func (db *DB[T]) Copy() *DB[T] {
 		WriteToNodeStrategy:  db.WriteToNodeStrategy,
 		ReadFromNodeStrategy: db.ReadFromNodeStrategy,
 		DefaultNodeStrategy:  db.DefaultNodeStrategy,
+		ReadRetryPolicy:      db.ReadRetryPolicy,
+		TxRetryPolicy:        db.TxRetryPolicy,
+		TxHooks:              db.TxHooks,
+		StructScanMode:       db.StructScanMode,
+		TenantScope:          db.TenantScope,
+		Hedger:               db.Hedger,
+		Tracer:               db.Tracer,
 		Ctx:                  db.Ctx,
 	}
 }
@@ -98,11 +120,23 @@ func (db *DB[T]) Copy() *DB[T] {
 func (db *DB[T]) GetConn(ctx context.Context, strategy GetNodeStragegy) (T, error) {
 	var t T
 
+	node, err := db.GetNode(ctx, strategy)
+	if err != nil {
+		return t, err
+	}
+
+	return node.DB(), nil
+}
+
+// GetNode returns cluster node picked by strategy.
+func (db *DB[T]) GetNode(ctx context.Context, strategy GetNodeStragegy) (cluster.Node[T], error) {
 	if !strategy.Wait {
 		node := db.Cluster.Node(strategy.Criteria)
 		if node == nil {
-			return t, fmt.Errorf("node (%s) not found", strategy.Criteria)
+			return nil, fmt.Errorf("node (%s) not found", strategy.Criteria)
 		}
+
+		return node, nil
 	}
 
 	waitCtx, cancel := context.WithTimeout(ctx, db.NodeWaitTimeout)
@@ -110,10 +144,10 @@ func (db *DB[T]) GetConn(ctx context.Context, strategy GetNodeStragegy) (T, erro
 
 	node, err := db.Cluster.WaitForNode(waitCtx, strategy.Criteria)
 	if err != nil {
-		return t, fmt.Errorf("wait for node (%s): %w", strategy.Criteria, err)
+		return nil, fmt.Errorf("wait for node (%s): %w", strategy.Criteria, err)
 	}
 
-	return node.DB(), nil
+	return node, nil
 }
 
 func (db *DB[T]) GetWriteToConn(ctx context.Context) (T, error) {
@@ -149,6 +183,8 @@ func newDB[T any]() *DB[T] {
 			Criteria: cluster.Primary,
 			Wait:     true,
 		},
+		ReadRetryPolicy: DefaultReadRetryPolicy(),
+		TxRetryPolicy:   DefaultTxRetryPolicy(),
 	}
 }
 
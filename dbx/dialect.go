@@ -0,0 +1,69 @@
+package dbx
+
+import "strings"
+
+// Dialect is SQL dialect of database, used by subsystems, which generate DDL and dialect-specific queries.
+type Dialect string
+
+const (
+	DialectPostgres  Dialect = "postgres"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// DialectFor returns dialect of database/sql driver. Unknown drivers are considered SQLite-compatible.
+func DialectFor(driverName string) Dialect {
+	switch driverName {
+	case "postgres", "pgx":
+		return DialectPostgres
+	case "mysql":
+		return DialectMySQL
+	case "sqlserver", "mssql":
+		return DialectSQLServer
+	default:
+		return DialectSQLite
+	}
+}
+
+// PlaceholderStyle returns positional placeholder style of dialect.
+func (d Dialect) PlaceholderStyle() PlaceholderStyle {
+	switch d {
+	case DialectPostgres:
+		return PlaceholderDollar
+	case DialectSQLServer:
+		return PlaceholderAtP
+	default:
+		return PlaceholderQuestion
+	}
+}
+
+// QuoteIdentifier quotes name as single identifier, e.g. table name.
+func (d Dialect) QuoteIdentifier(name string) string {
+	switch d {
+	case DialectMySQL:
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	case DialectSQLServer:
+		return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+	}
+}
+
+// ResetSessionQuery returns query, which resets session state of pinned connection, or empty string,
+// if dialect has no such query and connection must be discarded instead. Postgres query is DISCARD ALL
+// without DEALLOCATE ALL, so statement caches of drivers stay valid.
+func (d Dialect) ResetSessionQuery() string {
+	if d != DialectPostgres {
+		return ""
+	}
+
+	return `CLOSE ALL;
+SET SESSION AUTHORIZATION DEFAULT;
+RESET ALL;
+UNLISTEN *;
+SELECT pg_advisory_unlock_all();
+DISCARD PLANS;
+DISCARD TEMP;
+DISCARD SEQUENCES`
+}
@@ -0,0 +1,42 @@
+package dbx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is returned by single-row helpers, when query returned no rows.
+var ErrNotFound = errors.New("not found")
+
+// ErrPrimaryChanged is reported, when node, which held dedicated connection, is no longer primary.
+var ErrPrimaryChanged = errors.New("primary changed")
+
+// NotFound returns error, which wraps both ErrNotFound and driver-specific errNoRows
+// (sql.ErrNoRows or pgx.ErrNoRows), so callers may check any of them.
+func NotFound(errNoRows error) error {
+	return fmt.Errorf("%w: %w", ErrNotFound, errNoRows)
+}
+
+// RowsAffectedError is returned, when query affected unexpected number of rows.
+type RowsAffectedError struct {
+	Expected int64
+	Actual   int64
+}
+
+func (e *RowsAffectedError) Error() string {
+	return fmt.Sprintf("expected %d rows affected, got %d", e.Expected, e.Actual)
+}
+
+// ExpectRowsAffected returns *RowsAffectedError, if res affected other than expected number of rows.
+func ExpectRowsAffected(res Result, expected int64) error {
+	actual, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("get rows affected: %w", err)
+	}
+
+	if actual != expected {
+		return &RowsAffectedError{Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
@@ -1,6 +1,14 @@
 package dbx
 
-import "github.com/ValerySidorin/corex/errx"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/ValerySidorin/corex/errx"
+)
 
 func Scan[T any](rows Rows, pointers func(*T) []interface{}) ([]T, error) {
 	var res = []T{}
@@ -20,3 +28,83 @@ func Scan[T any](rows Rows, pointers func(*T) []interface{}) ([]T, error) {
 
 	return res, nil
 }
+
+// ForEach scans rows one by one and passes each of them to f. Iteration stops on the first error,
+// returned by f. Rows are always closed.
+func ForEach[T any](rows Rows, pointers func(*T) []interface{}, f func(T) error) error {
+	return ForEachContext(context.Background(), rows, pointers, f)
+}
+
+// ForEachContext scans rows one by one and passes each of them to f. Iteration stops on the first error,
+// returned by f, or when ctx is done. Rows are always closed.
+func ForEachContext[T any](ctx context.Context, rows Rows, pointers func(*T) []interface{}, f func(T) error) error {
+	defer closeRows(rows)
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return errx.Wrap("iterate rows", err)
+		}
+
+		var elem T
+		if err := rows.Scan(pointers(&elem)...); err != nil {
+			return errx.Wrap("row scan", err)
+		}
+
+		if err := f(elem); err != nil {
+			return errx.Wrap("row callback", err)
+		}
+	}
+
+	if rows.Err() != nil {
+		return errx.Wrap("rows err", rows.Err())
+	}
+
+	return nil
+}
+
+// closeRows closes both database/sql and pgx rows.
+func closeRows(rows Rows) {
+	switch r := rows.(type) {
+	case interface{ Close() error }:
+		_ = r.Close()
+	case interface{ Close() }:
+		r.Close()
+	}
+}
+
+// ScanOne scans the first row. ok is false, if there are no rows.
+func ScanOne[T any](rows Rows, pointers func(*T) []interface{}) (res T, ok bool, err error) {
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return res, false, errx.Wrap("rows err", rows.Err())
+		}
+
+		return res, false, nil
+	}
+
+	if err := rows.Scan(pointers(&res)...); err != nil {
+		return res, false, errx.Wrap("row scan", err)
+	}
+
+	return res, true, nil
+}
+
+// ValuePointers returns pointers function for Scan, which scans single column into T, if T is
+// a scalar (including sql.Scanner implementations and time.Time), or maps columns to fields
+// of T by `db` tags otherwise.
+func ValuePointers[T any](columns []string, mode StructScanMode) (func(*T) []interface{}, error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() == reflect.Struct &&
+		typ != reflect.TypeFor[time.Time]() &&
+		!reflect.PointerTo(typ).Implements(reflect.TypeFor[sql.Scanner]()) {
+		return StructPointers[T](columns, mode)
+	}
+
+	if len(columns) != 1 {
+		return nil, fmt.Errorf("expected 1 column for %s, got %d", typ, len(columns))
+	}
+
+	return func(elem *T) []interface{} {
+		return []interface{}{elem}
+	}, nil
+}
@@ -0,0 +1,272 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+)
+
+const (
+	DefaultHedgePercentile = 0.95
+	DefaultHedgeBudget     = 0.1
+	DefaultHedgeMaxBurst   = 10
+)
+
+// HedgePolicy describes, when reads are hedged: sent to second node, if first one has not answered in time.
+type HedgePolicy struct {
+	// Delay is a fixed time to wait for the first node. If zero, Percentile of node check latencies,
+	// observed by cluster, is used, once any node is checked.
+	Delay time.Duration
+	// Percentile of node check latencies in (0, 1], which is used as delay. DefaultHedgePercentile is used,
+	// if zero or negative, greater values are clamped to 1.
+	Percentile float64
+	// MinDelay is a lower bound of observed delay. Node checks are lighter than most reads,
+	// so it should cover typical read execution time.
+	MinDelay time.Duration
+	// Budget is a max ratio of hedged reads to all reads. DefaultHedgeBudget is used, if zero.
+	Budget float64
+	// MaxBurst is a max number of hedges, which may be sent at once after quiet period.
+	// DefaultHedgeMaxBurst is used, if zero.
+	MaxBurst float64
+}
+
+// Hedger tracks hedging budget of DB. It is shared by DB copies.
+type Hedger struct {
+	policy HedgePolicy
+
+	mu        sync.Mutex
+	budget    float64
+	hedged    int64
+	hedgeWins int64
+}
+
+// NewHedger returns hedger with policy.
+func NewHedger(policy HedgePolicy) *Hedger {
+	if policy.Percentile <= 0 {
+		policy.Percentile = DefaultHedgePercentile
+	}
+	policy.Percentile = min(policy.Percentile, 1)
+	if policy.Budget == 0 {
+		policy.Budget = DefaultHedgeBudget
+	}
+	if policy.MaxBurst == 0 {
+		policy.MaxBurst = DefaultHedgeMaxBurst
+	}
+
+	return &Hedger{
+		policy: policy,
+		budget: policy.MaxBurst,
+	}
+}
+
+// Stats returns number of hedged reads and number of reads, which were won by hedge.
+func (h *Hedger) Stats() (hedged, wins int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.hedged, h.hedgeWins
+}
+
+// Delay returns hedge delay for cluster with latency, e.g. Cluster.Latency. ok is false,
+// if delay is not fixed and no node is checked yet.
+func (h *Hedger) Delay(latency func(percentile float64) (time.Duration, bool)) (time.Duration, bool) {
+	if h.policy.Delay > 0 {
+		return h.policy.Delay, true
+	}
+
+	delay, ok := latency(h.policy.Percentile)
+	if !ok {
+		return 0, false
+	}
+
+	return max(delay, h.policy.MinDelay), true
+}
+
+// start deposits read share of budget.
+func (h *Hedger) start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.budget = min(h.budget+h.policy.Budget, h.policy.MaxBurst)
+}
+
+// allow withdraws single hedge from budget.
+func (h *Hedger) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.budget < 1 {
+		return false
+	}
+
+	h.budget--
+	h.hedged++
+	return true
+}
+
+func (h *Hedger) won() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hedgeWins++
+}
+
+type hedgeResult[R any] struct {
+	value   R
+	err     error
+	attempt int
+}
+
+// HedgedRead executes read f on connection picked by ReadFromNodeStrategy. If db has Hedger and the node
+// has not answered within hedge delay, f is executed on another node as well. The first succeeded result wins
+// and the other reads are cancelled, their results are passed to discard, e.g. to close rows. Read, that failed
+// with retryable error, is retried on another node at once according to ReadRetryPolicy. Without Hedger, read
+// is retried as DoRead does.
+//
+// Context of winning read is cancelled, when ctx is done or returned release function is called, e.g. when
+// rows are closed. Release may be skipped, if caller is not notified, when result is not used anymore,
+// as context of winning read is released with ctx (see attemptContext).
+// Release function is nil, if err is not nil.
+func HedgedRead[T, R any](ctx context.Context, db *DB[T], query string,
+	f func(ctx context.Context, conn T) (R, error), discard func(R)) (R, func(), error) {
+	var zero R
+
+	if db.Hedger == nil {
+		var res R
+		err := db.DoRead(ctx, query, func(conn T) error {
+			var err error
+			res, err = f(ctx, conn)
+			return err
+		})
+		if err != nil {
+			return zero, nil, err
+		}
+		return res, func() {}, nil
+	}
+
+	h := db.Hedger
+	h.start()
+
+	node, err := db.GetNode(ctx, db.ReadFromNodeStrategy)
+	if err != nil {
+		return zero, nil, err
+	}
+
+	results := make(chan hedgeResult[R], db.ReadRetryPolicy.MaxAttempts+1)
+	var (
+		tried   []cluster.Node[T]
+		cancels []context.CancelFunc
+		running int
+	)
+	run := func(node cluster.Node[T]) {
+		attemptCtx, cancel := attemptContext(ctx)
+		attempt := len(cancels)
+		tried = append(tried, node)
+		cancels = append(cancels, cancel)
+		running++
+
+		go func() {
+			value, err := f(attemptCtx, node.DB())
+			results <- hedgeResult[R]{value: value, err: err, attempt: attempt}
+		}()
+	}
+
+	run(node)
+
+	var timer <-chan time.Time
+	if delay, ok := h.Delay(db.Cluster.Latency); ok {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var (
+		errs   []error
+		failed int
+	)
+	for running > 0 {
+		select {
+		case <-timer:
+			// Hedges after delay are limited by budget, retries after error are not.
+			timer = nil
+			next := db.Cluster.NodeExcept(db.ReadFromNodeStrategy.Criteria, tried...)
+			if next != nil && h.allow() {
+				db.Tracer.hedge(ctx, tried[0], next)
+				run(next)
+			}
+		case res := <-results:
+			running--
+			if res.err == nil {
+				if res.attempt > 0 {
+					h.won()
+				}
+
+				// Losers are cancelled and their results are discarded, when they are done.
+				for attempt, cancel := range cancels {
+					if attempt != res.attempt {
+						cancel()
+					}
+				}
+				if running > 0 {
+					go func(running int) {
+						for ; running > 0; running-- {
+							if loser := <-results; loser.err == nil && discard != nil {
+								discard(loser.value)
+							}
+						}
+					}(running)
+				}
+
+				return res.value, cancels[res.attempt], nil
+			}
+
+			cancels[res.attempt]()
+			errs = append(errs, res.err)
+			failed++
+
+			// Read is retried, only when no other read is running, as DoRead does.
+			if running > 0 || failed >= db.ReadRetryPolicy.MaxAttempts || ctx.Err() != nil ||
+				!db.ReadRetryPolicy.shouldRetry(query, res.err) {
+				continue
+			}
+
+			if next := db.Cluster.NodeExcept(db.ReadFromNodeStrategy.Criteria, tried...); next != nil {
+				timer = nil
+				db.Tracer.readRetry(ctx, failed, tried[res.attempt], next, res.err)
+				run(next)
+			}
+		}
+	}
+
+	return zero, nil, errors.Join(errs...)
+}
+
+// attemptContext returns context of single read, which is done with ctx: it has the same deadline and is
+// cancelled by callback, registered on ctx. It is not a child of ctx, so it needs no cancelling: its deadline
+// timer and callback are dropped, when ctx is done. Returned function cancels it earlier.
+func attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	var (
+		attemptCtx context.Context
+		cancel     context.CancelFunc
+	)
+	if deadline, ok := ctx.Deadline(); ok {
+		attemptCtx, cancel = context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	} else {
+		attemptCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		// Expired deadline of ctx expires attempt as well, so it fails with the same error.
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			cancel()
+		}
+	})
+
+	return attemptCtx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,139 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgedRead(t *testing.T) {
+	db := newTestDB(t, "first", "second")
+	db.Hedger = NewHedger(HedgePolicy{Delay: 10 * time.Millisecond, MaxBurst: 1})
+
+	var traced []string
+	db.Tracer.Hedge = func(ctx context.Context, slow, next cluster.Node[string]) {
+		traced = append(traced, slow.Addr(), next.Addr())
+	}
+
+	var (
+		calls     atomic.Int32
+		winnerCtx context.Context
+	)
+	slowCancelled := make(chan struct{})
+	read := func(ctx context.Context, conn string) (string, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			close(slowCancelled)
+			return "", ctx.Err()
+		}
+		winnerCtx = ctx
+		return conn, nil
+	}
+
+	// Slow read is hedged, hedge wins and slow read is cancelled.
+	res, release, err := HedgedRead(context.Background(), db, "select 1", read, nil)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, res)
+	<-slowCancelled
+	assert.Len(t, traced, 2)
+	assert.Equal(t, res, traced[1])
+
+	// Context of winning read lives until release.
+	assert.Nil(t, winnerCtx.Err())
+	release()
+	assert.ErrorIs(t, winnerCtx.Err(), context.Canceled)
+
+	hedged, wins := db.Hedger.Stats()
+	assert.Equal(t, int64(1), hedged)
+	assert.Equal(t, int64(1), wins)
+
+	// Budget is spent, so read is not hedged.
+	calls.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = HedgedRead(ctx, db, "select 1", func(ctx context.Context, conn string) (string, error) {
+		calls.Add(1)
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestHedgedReadRetry(t *testing.T) {
+	db := newTestDB(t, "first", "second", "third")
+	db.Hedger = NewHedger(HedgePolicy{Delay: time.Minute})
+
+	read := func(tried *[]string, failures int) func(ctx context.Context, conn string) (string, error) {
+		return func(ctx context.Context, conn string) (string, error) {
+			*tried = append(*tried, conn)
+			if len(*tried) <= failures {
+				return "", driver.ErrBadConn
+			}
+			return conn, nil
+		}
+	}
+
+	// Failed reads are retried on other nodes up to MaxAttempts.
+	var tried []string
+	db.ReadRetryPolicy.MaxAttempts = 3
+	res, release, err := HedgedRead(context.Background(), db, "select 1", read(&tried, 2), nil)
+	assert.Nil(t, err)
+	release()
+	assert.ElementsMatch(t, []string{"first", "second", "third"}, tried)
+	assert.Equal(t, tried[2], res)
+
+	tried = nil
+	db.ReadRetryPolicy.MaxAttempts = 2
+	_, _, err = HedgedRead(context.Background(), db, "select 1", read(&tried, 2), nil)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Len(t, tried, 2)
+}
+
+func TestHedgerDelay(t *testing.T) {
+	h := NewHedger(HedgePolicy{MinDelay: 5 * time.Millisecond})
+
+	_, ok := h.Delay(func(percentile float64) (time.Duration, bool) {
+		return 0, false
+	})
+	assert.False(t, ok)
+
+	delay, ok := h.Delay(func(percentile float64) (time.Duration, bool) {
+		assert.Equal(t, DefaultHedgePercentile, percentile)
+		return time.Millisecond, true
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Millisecond, delay)
+
+	// Percentile out of (0, 1] does not index latencies out of range.
+	for percentile, expected := range map[float64]float64{-1: DefaultHedgePercentile, 1.5: 1} {
+		_, _ = NewHedger(HedgePolicy{Percentile: percentile}).Delay(func(percentile float64) (time.Duration, bool) {
+			assert.Equal(t, expected, percentile)
+			return 0, false
+		})
+	}
+
+	// Cluster reports latencies of node checks.
+	db := newTestDB(t, "first")
+	_, ok = h.Delay(db.Cluster.Latency)
+	assert.True(t, ok)
+}
+
+func TestAttemptContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attemptCtx, _ := attemptContext(ctx)
+	cancel()
+	<-attemptCtx.Done()
+	assert.ErrorIs(t, attemptCtx.Err(), context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	attemptCtx, _ = attemptContext(ctx)
+	<-attemptCtx.Done()
+	assert.ErrorIs(t, attemptCtx.Err(), context.DeadlineExceeded)
+}
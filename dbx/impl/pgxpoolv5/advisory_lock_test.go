@@ -0,0 +1,70 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+
+	var held, broken atomic.Bool
+	srv := newFakePG(t, func(sql string) fakeResult {
+		switch {
+		case strings.Contains(sql, "pg_try_advisory_lock"):
+			return fakeResult{columns: []string{"pg_try_advisory_lock"}, rows: [][]any{{!held.Load()}}}
+		case strings.Contains(sql, "pg_advisory_lock"):
+			return fakeResult{columns: []string{"bool"}, rows: [][]any{{true}}}
+		case strings.Contains(sql, "pg_advisory_unlock"):
+			return fakeResult{columns: []string{"pg_advisory_unlock"}, rows: [][]any{{true}}}
+		case strings.Contains(sql, "ping") && broken.Load():
+			return fakeResult{drop: true}
+		}
+		return fakeResult{}
+	})
+	db := newFakeDB(t, srv)
+	_, err := db.Cluster.WaitForAlive(ctx)
+	assert.Nil(t, err)
+
+	t.Run("acquire and release", func(t *testing.T) {
+		lock, err := db.AcquireLock(ctx, 42)
+		assert.Nil(t, err)
+
+		// Lock connection is pinned, so other queries use another one.
+		_, err = db.Exec(ctx, "SELECT 'other'")
+		assert.Nil(t, err)
+		conn := srv.ConnOf("pg_advisory_lock(42)")
+		assert.NotEqual(t, conn, srv.ConnOf("SELECT 'other'"))
+
+		assert.Nil(t, lock.Check(ctx))
+		assert.Nil(t, lock.Release(ctx))
+		assert.Equal(t, conn, srv.ConnOf("pg_advisory_unlock(42)"))
+		assert.False(t, srv.Closed(conn))
+	})
+
+	t.Run("try acquire held", func(t *testing.T) {
+		held.Store(true)
+		defer held.Store(false)
+
+		lock, ok, err := db.TryAcquireLock(ctx, 43)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, lock)
+	})
+
+	t.Run("check broken conn", func(t *testing.T) {
+		lock, ok, err := db.TryAcquireLock(ctx, 44)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+
+		broken.Store(true)
+		defer broken.Store(false)
+
+		assert.ErrorContains(t, lock.Check(ctx), "ping")
+		assert.NotNil(t, lock.Release(ctx))
+	})
+}
@@ -0,0 +1,148 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// BatchResult holds result of single batch item. It is filled by DB.ExecBatch.
+type BatchResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// Get returns result value and error.
+func (r *BatchResult[T]) Get() (T, error) {
+	return r.Value, r.Err
+}
+
+type batchItem interface {
+	read(br pgx.BatchResults) error
+}
+
+// Batch is a typed batch builder. Every queued item is scanned into its own BatchResult.
+type Batch struct {
+	batch pgx.Batch
+	items []batchItem
+}
+
+// NewBatch returns empty typed batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Len returns number of queued items.
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Exec queues query, which result is a command tag.
+func (b *Batch) Exec(sql string, args ...any) *BatchResult[pgconn.CommandTag] {
+	res := &BatchResult[pgconn.CommandTag]{}
+	b.queue(sql, args, &batchExec{res: res})
+	return res
+}
+
+// QueueQuery queues query, which rows are scanned with pointers.
+func QueueQuery[T any](b *Batch, sql string, pointers func(*T) []interface{}, args ...any) *BatchResult[[]T] {
+	res := &BatchResult[[]T]{}
+	b.queue(sql, args, &batchQuery[T]{res: res, pointers: pointers})
+	return res
+}
+
+// QueueQueryRow queues query, which first row is scanned with pointers.
+// Error, wrapping both dbx.ErrNotFound and pgx.ErrNoRows, is set, if there are no rows.
+func QueueQueryRow[T any](b *Batch, sql string, pointers func(*T) []interface{}, args ...any) *BatchResult[T] {
+	res := &BatchResult[T]{}
+	b.queue(sql, args, &batchQueryRow[T]{res: res, pointers: pointers})
+	return res
+}
+
+func (b *Batch) queue(sql string, args []any, item batchItem) {
+	b.batch.Queue(sql, args...)
+	b.items = append(b.items, item)
+}
+
+type batchExec struct {
+	res *BatchResult[pgconn.CommandTag]
+}
+
+func (i *batchExec) read(br pgx.BatchResults) error {
+	i.res.Value, i.res.Err = br.Exec()
+	return i.res.Err
+}
+
+type batchQuery[T any] struct {
+	res      *BatchResult[[]T]
+	pointers func(*T) []interface{}
+}
+
+func (i *batchQuery[T]) read(br pgx.BatchResults) error {
+	rows, err := br.Query()
+	if err != nil {
+		i.res.Err = err
+		return err
+	}
+	defer rows.Close()
+
+	i.res.Value, i.res.Err = dbx.Scan(rows, i.pointers)
+	return i.res.Err
+}
+
+type batchQueryRow[T any] struct {
+	res      *BatchResult[T]
+	pointers func(*T) []interface{}
+}
+
+func (i *batchQueryRow[T]) read(br pgx.BatchResults) error {
+	rows, err := br.Query()
+	if err != nil {
+		i.res.Err = err
+		return err
+	}
+	defer rows.Close()
+
+	var ok bool
+	i.res.Value, ok, i.res.Err = dbx.ScanOne(rows, i.pointers)
+	if i.res.Err == nil && !ok {
+		i.res.Err = dbx.NotFound(pgx.ErrNoRows)
+	}
+
+	return i.res.Err
+}
+
+// ExecBatch sends typed batch and collects results of all items in one call. Every item gets its own
+// error; returned error joins errors of all failed items. Batch is routed the same way as in SendBatch.
+func (db *DB) ExecBatch(ctx context.Context, b *Batch) error {
+	br := db.SendBatch(ctx, &b.batch)
+
+	var errs []error
+	for idx, item := range b.items {
+		if err := item.read(br); err != nil {
+			errs = append(errs, fmt.Errorf("batch item %d: %w", idx, err))
+		}
+	}
+
+	if err := br.Close(); err != nil && len(errs) == 0 {
+		errs = append(errs, errx.Wrap("close batch", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// isReadOnlyBatch reports whether all batch queries are reads without locks and side effects.
+func isReadOnlyBatch(b *pgx.Batch) bool {
+	for _, qq := range b.QueuedQueries {
+		if !dbx.IsReadOnlyQuery(qq.SQL) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecBatch(t *testing.T) {
+	handler := func(sql string) fakeResult {
+		switch {
+		case strings.HasPrefix(sql, "SELECT id"):
+			return fakeResult{columns: []string{"id"}, rows: [][]any{{1}, {2}}}
+		case strings.HasPrefix(sql, "SELECT name"):
+			return fakeResult{columns: []string{"name"}}
+		case strings.HasPrefix(sql, "UPDATE"):
+			return fakeResult{tag: "UPDATE 3"}
+		case strings.HasPrefix(sql, "DELETE"):
+			return fakeResult{err: "delete failed"}
+		}
+		return fakeResult{}
+	}
+	primary, standby := newFakePG(t, handler), newFakePG(t, handler)
+
+	db, err := NewDB([]string{primary.dsn(), standby.dsn()},
+		WithNodeChecker(func(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+			return strings.Contains(primary.dsn(), fmt.Sprintf(":%d/", pool.Config().ConnConfig.Port)), nil
+		}))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	_, err = db.Cluster.WaitForStandby(context.Background())
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	t.Run("reads go to standby", func(t *testing.T) {
+		b := NewBatch()
+		ids := QueueQuery(b, "SELECT id FROM reads", func(id *int) []interface{} { return []interface{}{id} })
+		name := QueueQueryRow(b, "SELECT name FROM reads", func(name *string) []interface{} {
+			return []interface{}{name}
+		})
+
+		err := db.ExecBatch(ctx, b)
+		assert.ErrorIs(t, err, dbx.ErrNotFound)
+		assert.ErrorContains(t, err, "batch item 1")
+
+		value, err := ids.Get()
+		assert.Nil(t, err)
+		assert.Equal(t, []int{1, 2}, value)
+		assert.ErrorIs(t, name.Err, dbx.ErrNotFound)
+
+		assert.NotZero(t, standby.ConnOf("SELECT id FROM reads"))
+		assert.Zero(t, primary.ConnOf("SELECT id FROM reads"))
+	})
+
+	t.Run("mixed batch goes to primary", func(t *testing.T) {
+		b := NewBatch()
+		ids := QueueQuery(b, "SELECT id FROM mixed", func(id *int) []interface{} { return []interface{}{id} })
+		tag := b.Exec("UPDATE mixed SET name = 'foo'")
+
+		assert.Nil(t, db.ExecBatch(ctx, b))
+		assert.Len(t, ids.Value, 2)
+		assert.Equal(t, int64(3), tag.Value.RowsAffected())
+
+		assert.NotZero(t, primary.ConnOf("SELECT id FROM mixed"))
+		assert.Zero(t, standby.ConnOf("SELECT id FROM mixed"))
+	})
+
+	t.Run("item errors are joined", func(t *testing.T) {
+		b := NewBatch()
+		deleted := b.Exec("DELETE FROM mixed")
+		name := QueueQueryRow(b, "SELECT name FROM mixed", func(name *string) []interface{} {
+			return []interface{}{name}
+		})
+
+		err := db.ExecBatch(ctx, b)
+		assert.ErrorContains(t, err, "batch item 0")
+		assert.ErrorContains(t, err, "delete failed")
+		assert.ErrorContains(t, deleted.Err, "delete failed")
+		assert.NotNil(t, name.Err)
+		assert.ErrorContains(t, err, "batch item 1")
+	})
+
+	t.Run("close error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := db.ExecBatch(ctx, NewBatch())
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "close batch")
+	})
+}
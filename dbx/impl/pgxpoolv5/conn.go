@@ -0,0 +1,64 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const DefaultSessionResetTimeout = 15 * time.Second
+
+// WithConn pins single connection from strategy node and executes f with it. Every *DB call,
+// made with passed db or ctx, is routed to pinned connection, so session state (SET, temp tables,
+// session advisory locks) is visible to all of them. Transactions, started inside f, are begun
+// on pinned connection. Tenant scope of ctx is applied once, when connection is pinned.
+//
+// Session state is reset before connection is returned to pool. If reset fails or connection
+// is left in transaction, it is closed instead.
+//
+// If db or ctx is already bound to transaction or pinned connection, f is executed with it.
+func (db *DB) WithConn(ctx context.Context, strategy dbx.GetNodeStragegy,
+	f func(ctx context.Context, db *DB) error) error {
+	if joined := db.joinTx(ctx); joined.tx != nil || joined.conn != nil {
+		return f(ctx, joined)
+	}
+
+	pool, err := db.GetConn(ctx, strategy)
+	if err != nil {
+		return errx.Wrap("wait for conn", err)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return err
+	}
+
+	newDB := db.copy()
+	newDB.conn = conn
+	newDB.Ctx = newDB.ContextWithConn(ctx, newDB)
+
+	defer releaseConn(ctx, conn)
+
+	return errx.Wrap("exec func with conn", f(newDB.Ctx, newDB))
+}
+
+// releaseConn resets session state of conn and returns it to pool.
+func releaseConn(ctx context.Context, conn *pgxpool.Conn) {
+	if conn.Conn().PgConn().TxStatus() != 'I' {
+		_ = conn.Hijack().Close(context.Background())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultSessionResetTimeout)
+	defer cancel()
+
+	if _, err := conn.Exec(ctx, dbx.DialectPostgres.ResetSessionQuery()); err != nil {
+		_ = conn.Hijack().Close(context.Background())
+		return
+	}
+
+	conn.Release()
+}
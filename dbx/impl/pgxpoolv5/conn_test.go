@@ -0,0 +1,151 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeDB(t *testing.T, srv *fakePG) *DB {
+	db, err := NewDB([]string{srv.dsn()}, WithNodeChecker(nopNodeChecker))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+func TestWithConn(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("pinned and reset", func(t *testing.T) {
+		srv := newFakePG(t, func(sql string) fakeResult {
+			if strings.HasPrefix(sql, "SELECT current_setting") {
+				return fakeResult{columns: []string{"current_setting"}, rows: [][]any{{"42"}}}
+			}
+			return fakeResult{}
+		})
+		db := newFakeDB(t, srv)
+
+		err := db.WithConn(ctx, db.WriteToNodeStrategy, func(ctx context.Context, conn *DB) error {
+			if _, err := conn.Exec(ctx, "SET app.answer = 42"); err != nil {
+				return err
+			}
+
+			// Outer db joins pinned connection through ctx.
+			var answer string
+			return db.QueryRow(ctx, "SELECT current_setting('app.answer')").Scan(&answer)
+		})
+		assert.Nil(t, err)
+
+		conn := srv.ConnOf("SET app.answer")
+		queries := srv.Queries(conn)
+		assert.Contains(t, queries, "SELECT current_setting('app.answer')")
+		assert.Equal(t, dbx.DialectPostgres.ResetSessionQuery(), queries[len(queries)-1])
+
+		stat := db.Cluster.Nodes()[0].DB().Stat()
+		assert.Equal(t, int32(0), stat.AcquiredConns())
+		assert.Equal(t, int32(1), stat.TotalConns())
+	})
+
+	t.Run("left in tx", func(t *testing.T) {
+		srv := newFakePG(t, nil)
+		db := newFakeDB(t, srv)
+
+		err := db.WithConn(ctx, db.WriteToNodeStrategy, func(ctx context.Context, conn *DB) error {
+			_, err := conn.Exec(ctx, "BEGIN")
+			return err
+		})
+		assert.Nil(t, err)
+
+		conn := srv.ConnOf("BEGIN")
+		assert.NotContains(t, srv.Queries(conn), dbx.DialectPostgres.ResetSessionQuery())
+		assertConnDiscarded(t, db.Cluster.Nodes()[0].DB(), srv, conn)
+	})
+
+	t.Run("reset failed", func(t *testing.T) {
+		srv := newFakePG(t, func(sql string) fakeResult {
+			if strings.Contains(sql, "DISCARD") {
+				return fakeResult{err: "reset failed"}
+			}
+			return fakeResult{}
+		})
+		db := newFakeDB(t, srv)
+
+		err := db.WithConn(ctx, db.WriteToNodeStrategy, func(ctx context.Context, conn *DB) error {
+			_, err := conn.Exec(ctx, "SET app.answer = 42")
+			return err
+		})
+		assert.Nil(t, err)
+
+		assertConnDiscarded(t, db.Cluster.Nodes()[0].DB(), srv, srv.ConnOf("SET app.answer"))
+	})
+}
+
+func assertConnDiscarded(t *testing.T, pool *pgxpool.Pool, srv *fakePG, conn int) {
+	t.Helper()
+
+	assert.Equal(t, int32(0), pool.Stat().TotalConns())
+	assert.Eventually(t, func() bool { return srv.Closed(conn) }, time.Second, 10*time.Millisecond)
+}
+
+func TestTenantScopeRelease(t *testing.T) {
+	srv := newFakePG(t, func(sql string) fakeResult {
+		if sql == "SELECT 1" {
+			return fakeResult{columns: []string{"n"}, rows: [][]any{{1}}}
+		}
+		return fakeResult{}
+	})
+
+	// Single pooled connection is shared by scoped and unscoped statements.
+	db, err := NewDB([]string{srv.dsn() + "&pool_max_conns=1"}, WithNodeChecker(nopNodeChecker))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	scoped := db.WithTenantScope(&dbx.TenantScope{Setting: "app.tenant_id"})
+	ctx := dbx.ContextWithTenant(context.Background(), "a")
+
+	// Simple protocol interpolates arguments with spaces around them.
+	apply := "SELECT set_config( 'app.tenant_id' ,  'a' , false)"
+	reset := "SELECT set_config( 'app.tenant_id' ,  '' , false)"
+
+	_, err = scoped.Exec(ctx, "DELETE FROM users")
+	assert.Nil(t, err)
+
+	var n int
+	assert.Nil(t, scoped.QueryRow(ctx, "SELECT 1").Scan(&n))
+
+	rows, err := scoped.Query(ctx, "SELECT 1")
+	assert.Nil(t, err)
+	for rows.Next() {
+	}
+	assert.Nil(t, rows.Err())
+
+	// Unscoped statement must not run as tenant.
+	_, err = db.Exec(context.Background(), "DELETE FROM orders")
+	assert.Nil(t, err)
+
+	var queries []string
+	for _, q := range srv.Queries(srv.ConnOf("DELETE FROM orders")) {
+		if q != "-- ping" {
+			queries = append(queries, q)
+		}
+	}
+	assert.Equal(t, []string{
+		apply, "DELETE FROM users", reset,
+		apply, "SELECT 1", reset,
+		apply, "SELECT 1", reset,
+		"DELETE FROM orders",
+	}, queries)
+}
+
+func TestPrepareNotPinned(t *testing.T) {
+	db := newFakeDB(t, newFakePG(t, nil))
+
+	_, err := db.Prepare(context.Background(), "stmt", "SELECT 1")
+	assert.ErrorIs(t, err, ErrNotPinned)
+}
@@ -0,0 +1,25 @@
+package pgxpoolv5
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX is an interface, which sqlc-generated code for pgx/v5 expects.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+var _ DBTX = &DB{}
+
+// DBTX returns sqlc-compatible adapter of db. Queries are routed to cluster nodes
+// and join transaction from context, as *DB methods do.
+func (db *DB) DBTX() DBTX {
+	return db
+}
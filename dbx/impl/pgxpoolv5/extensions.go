@@ -5,6 +5,7 @@ import (
 
 	"github.com/ValerySidorin/corex/dbx"
 	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
 )
 
 // Query is a generic query helper with context.
@@ -19,3 +20,102 @@ func Query[T any](ctx context.Context, db *DB, query string,
 	res, err := dbx.Scan(rows, pointers)
 	return res, errx.Wrap("scan", err)
 }
+
+// QueryForEach is a generic query helper with context, which streams rows to f one by one.
+// Iteration stops on the first error, returned by f, or when ctx is done.
+func QueryForEach[T any](ctx context.Context, db *DB, query string,
+	pointers func(*T) []interface{}, f func(T) error, args ...any) error {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return errx.Wrap("generic query for each", err)
+	}
+
+	return errx.Wrap("for each", dbx.ForEachContext(ctx, rows, pointers, f))
+}
+
+// QueryStructs is a generic query helper with context, which scans rows into structs by `db` tags.
+func QueryStructs[T any](ctx context.Context, db *DB, query string, args ...any) ([]T, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap("generic query structs", err)
+	}
+	defer rows.Close()
+
+	res, err := dbx.ScanStructs[T](&queryRows{Rows: rows}, db.StructScanMode)
+	return res, errx.Wrap("scan structs", err)
+}
+
+// QueryStruct is a generic query helper with context, which scans the first row into struct by `db` tags.
+// Error, wrapping both dbx.ErrNotFound and pgx.ErrNoRows, is returned, if there are no rows.
+func QueryStruct[T any](ctx context.Context, db *DB, query string, args ...any) (T, error) {
+	return queryOne(ctx, db, query, func(columns []string) (func(*T) []interface{}, error) {
+		return dbx.StructPointers[T](columns, db.StructScanMode)
+	}, args...)
+}
+
+// QueryOne is a generic query helper with context, which scans the first row.
+// Error, wrapping both dbx.ErrNotFound and pgx.ErrNoRows, is returned, if there are no rows.
+func QueryOne[T any](ctx context.Context, db *DB, query string,
+	pointers func(*T) []interface{}, args ...any) (T, error) {
+	return queryOne(ctx, db, query, func([]string) (func(*T) []interface{}, error) {
+		return pointers, nil
+	}, args...)
+}
+
+// Get is a generic query helper with context, which scans the first row into T. Scalars are scanned
+// from single column, structs are scanned by `db` tags.
+// Error, wrapping both dbx.ErrNotFound and pgx.ErrNoRows, is returned, if there are no rows.
+func Get[T any](ctx context.Context, db *DB, query string, args ...any) (T, error) {
+	return queryOne(ctx, db, query, func(columns []string) (func(*T) []interface{}, error) {
+		return dbx.ValuePointers[T](columns, db.StructScanMode)
+	}, args...)
+}
+
+func queryOne[T any](ctx context.Context, db *DB, query string,
+	pointersBuilder func(columns []string) (func(*T) []interface{}, error), args ...any) (T, error) {
+	var res T
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return res, errx.Wrap("generic query one", err)
+	}
+	defer rows.Close()
+
+	columns, err := (&queryRows{Rows: rows}).Columns()
+	if err != nil {
+		return res, errx.Wrap("get columns", err)
+	}
+
+	pointers, err := pointersBuilder(columns)
+	if err != nil {
+		return res, errx.Wrap("get pointers", err)
+	}
+
+	res, ok, err := dbx.ScanOne(rows, pointers)
+	if err != nil {
+		return res, errx.Wrap("scan one", err)
+	}
+	if !ok {
+		return res, dbx.NotFound(pgx.ErrNoRows)
+	}
+
+	return res, nil
+}
+
+// NewPaginator returns keyset paginator over query. Pages are fetched with Query,
+// so they are read from ReadFromNodeStrategy node, unless db is bound to transaction.
+// See dbx.Paginator for query and keys requirements.
+func NewPaginator[T any](db *DB, query string, keys []dbx.KeysetColumn, pageSize int,
+	pointers func(*T) []interface{}, key func(*T) []any, args ...any) *dbx.Paginator[T] {
+	return &dbx.Paginator[T]{
+		Query:    query,
+		Args:     args,
+		Keys:     keys,
+		PageSize: pageSize,
+		Key:      key,
+		Fetch: func(ctx context.Context, query string, args ...any) ([]T, error) {
+			return Query(ctx, db, query, pointers, args...)
+		},
+		Style: dbx.PlaceholderDollar,
+	}
+}
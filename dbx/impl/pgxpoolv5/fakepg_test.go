@@ -0,0 +1,254 @@
+package pgxpoolv5
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeResult is a result of query to fakePG. Column types are inferred from values of the first row:
+// int values are int8, bool values are bool, others are text. drop closes connection instead of responding.
+type fakeResult struct {
+	columns []string
+	rows    [][]any
+	tag     string
+	err     string
+	drop    bool
+}
+
+type fakeQuery struct {
+	conn int
+	sql  string
+}
+
+// fakePG is a minimal Postgres server, which speaks simple query protocol. Clients must connect
+// with default_query_exec_mode=simple_protocol, see dsn.
+type fakePG struct {
+	ln      net.Listener
+	handler func(sql string) fakeResult
+
+	mu       sync.Mutex
+	nextConn int
+	queries  []fakeQuery
+	closed   map[int]bool
+}
+
+func newFakePG(t *testing.T, handler func(sql string) fakeResult) *fakePG {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakePG{
+		ln:      ln,
+		handler: handler,
+		closed:  make(map[int]bool),
+	}
+	go s.serve()
+
+	return s
+}
+
+func (s *fakePG) dsn() string {
+	return fmt.Sprintf("postgres://test@%s/test?sslmode=disable&default_query_exec_mode=simple_protocol",
+		s.ln.Addr())
+}
+
+// Queries returns queries, received by connection.
+func (s *fakePG) Queries(conn int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []string
+	for _, q := range s.queries {
+		if q.conn == conn {
+			res = append(res, q.sql)
+		}
+	}
+
+	return res
+}
+
+// ConnOf returns id of connection, which received the last query, containing substr.
+func (s *fakePG) ConnOf(substr string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.queries) - 1; i >= 0; i-- {
+		if strings.Contains(s.queries[i].sql, substr) {
+			return s.queries[i].conn
+		}
+	}
+
+	return 0
+}
+
+// Closed reports whether client closed connection.
+func (s *fakePG) Closed(conn int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed[conn]
+}
+
+func (s *fakePG) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.nextConn++
+		id := s.nextConn
+		s.mu.Unlock()
+
+		go func() {
+			defer conn.Close()
+			_ = s.serveConn(id, pgproto3.NewBackend(conn, conn))
+
+			s.mu.Lock()
+			s.closed[id] = true
+			s.mu.Unlock()
+		}()
+	}
+}
+
+func (s *fakePG) serveConn(id int, backend *pgproto3.Backend) error {
+	startup, err := backend.ReceiveStartupMessage()
+	if err != nil {
+		return err
+	}
+	if _, ok := startup.(*pgproto3.CancelRequest); ok {
+		return nil
+	}
+
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "server_version", Value: "16.0"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.BackendKeyData{ProcessID: uint32(id)})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return err
+	}
+
+	txStatus := byte('I')
+	for {
+		msg, err := backend.Receive()
+		if err != nil {
+			return err
+		}
+
+		query, ok := msg.(*pgproto3.Query)
+		if !ok {
+			if _, ok := msg.(*pgproto3.Terminate); ok {
+				return nil
+			}
+			return fmt.Errorf("unsupported message %T", msg)
+		}
+
+		s.mu.Lock()
+		s.queries = append(s.queries, fakeQuery{conn: id, sql: query.String})
+		s.mu.Unlock()
+
+		// Statements of multi-statement query (e.g. batch) get their own results, the first error
+		// skips the rest of them, as in Postgres.
+		for _, stmt := range strings.Split(query.String, ";") {
+			var failed bool
+			if txStatus, failed, err = s.exec(backend, strings.TrimSpace(stmt), txStatus); err != nil {
+				return err
+			}
+			if failed {
+				break
+			}
+		}
+
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: txStatus})
+		if err := backend.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// exec sends result of single statement and returns new transaction status and whether statement failed.
+func (s *fakePG) exec(backend *pgproto3.Backend, stmt string, txStatus byte) (byte, bool, error) {
+	res := fakeResult{tag: "SELECT 0"}
+	if s.handler != nil {
+		res = s.handler(stmt)
+	}
+
+	sql := strings.ToLower(stmt)
+	switch {
+	case res.drop:
+		return txStatus, true, errors.New("connection dropped")
+	case res.err != "":
+		backend.Send(&pgproto3.ErrorResponse{Severity: "ERROR", Code: "XX000", Message: res.err})
+		if txStatus == 'T' {
+			txStatus = 'E'
+		}
+		return txStatus, true, nil
+	case strings.HasPrefix(sql, "begin"):
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte("BEGIN")})
+		return 'T', false, nil
+	case strings.HasPrefix(sql, "commit"), strings.HasPrefix(sql, "rollback"):
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(strings.ToUpper(sql))})
+		return 'I', false, nil
+	default:
+		return txStatus, false, sendRows(backend, res)
+	}
+}
+
+func sendRows(backend *pgproto3.Backend, res fakeResult) error {
+	if len(res.columns) > 0 {
+		fields := make([]pgproto3.FieldDescription, 0, len(res.columns))
+		for i, name := range res.columns {
+			oid, size := uint32(pgtype.TextOID), int16(-1)
+			if len(res.rows) > 0 {
+				switch res.rows[0][i].(type) {
+				case int:
+					oid, size = pgtype.Int8OID, 8
+				case bool:
+					oid, size = pgtype.BoolOID, 1
+				}
+			}
+			fields = append(fields, pgproto3.FieldDescription{
+				Name: []byte(name), DataTypeOID: oid, DataTypeSize: size, TypeModifier: -1,
+			})
+		}
+		backend.Send(&pgproto3.RowDescription{Fields: fields})
+	}
+
+	for _, row := range res.rows {
+		values := make([][]byte, 0, len(row))
+		for _, v := range row {
+			switch v := v.(type) {
+			case int:
+				values = append(values, []byte(strconv.Itoa(v)))
+			case bool:
+				values = append(values, []byte(strconv.FormatBool(v)[:1]))
+			case string:
+				values = append(values, []byte(v))
+			default:
+				return errors.New("unsupported value type")
+			}
+		}
+		backend.Send(&pgproto3.DataRow{Values: values})
+	}
+
+	tag := res.tag
+	if tag == "" {
+		tag = "SELECT " + strconv.Itoa(len(res.rows))
+	}
+	backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(tag)})
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedgedQuery(t *testing.T) {
+	// The first read is slow, so it is hedged to another node.
+	var reads atomic.Int32
+	handler := func(name string) func(sql string) fakeResult {
+		return func(sql string) fakeResult {
+			if !strings.HasPrefix(sql, "SELECT name") {
+				return fakeResult{}
+			}
+			if reads.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			return fakeResult{columns: []string{"name"}, rows: [][]any{{name}}}
+		}
+	}
+	first, second := newFakePG(t, handler("first")), newFakePG(t, handler("second"))
+
+	db, err := NewDB([]string{first.dsn(), second.dsn()}, WithNodeChecker(nopNodeChecker))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	_, err = db.Cluster.WaitForAlive(context.Background())
+	assert.Nil(t, err)
+	db = db.WithHedgedReads(dbx.HedgePolicy{Delay: 10 * time.Millisecond})
+
+	started := time.Now()
+	rows, err := db.Query(context.Background(), "SELECT name FROM node")
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(started), 200*time.Millisecond)
+
+	// Rows of winning read stay valid after Query returns.
+	var names []string
+	for rows.Next() {
+		var name string
+		assert.Nil(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Nil(t, rows.Err())
+	rows.Close()
+	assert.Len(t, names, 1)
+
+	hedged, wins := db.Hedger.Stats()
+	assert.Equal(t, int64(1), hedged)
+	assert.Equal(t, int64(1), wins)
+
+	// Loser is cancelled and its connection is not left acquired.
+	for _, node := range db.Cluster.Nodes() {
+		pool := node.DB()
+		assert.Eventually(t, func() bool { return pool.Stat().AcquiredConns() == 0 }, 2*time.Second, 10*time.Millisecond)
+	}
+}
@@ -1,8 +1,11 @@
 package pgxpoolv5
 
 import (
+	"context"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type errRow struct {
@@ -13,6 +16,19 @@ func (r *errRow) Scan(dest ...any) error {
 	return r.err
 }
 
+type retryRow struct {
+	db   *DB
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+func (r *retryRow) Scan(dest ...any) error {
+	return r.db.DoRead(r.ctx, r.sql, func(pool *pgxpool.Pool) error {
+		return r.db.queryRow(r.ctx, pool, r.sql, r.args...).Scan(dest...)
+	})
+}
+
 type errBatchResults struct {
 	err error
 }
@@ -34,3 +50,53 @@ func (r *errBatchResults) QueryRow() pgx.Row {
 func (r *errBatchResults) Close() error {
 	return r.err
 }
+
+// releaseRows releases its connection, when rows are closed.
+type releaseRows struct {
+	pgx.Rows
+	release func()
+}
+
+func (r *releaseRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+
+	r.Close()
+	return false
+}
+
+func (r *releaseRows) Close() {
+	r.Rows.Close()
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+}
+
+// releaseRow releases its connection, when row is scanned.
+type releaseRow struct {
+	row     pgx.Row
+	release func()
+}
+
+func (r *releaseRow) Scan(dest ...any) error {
+	defer r.release()
+	return r.row.Scan(dest...)
+}
+
+// releaseBatchResults releases its connection, when batch results are closed.
+type releaseBatchResults struct {
+	pgx.BatchResults
+	release func()
+}
+
+func (r *releaseBatchResults) Close() error {
+	err := r.BatchResults.Close()
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+
+	return err
+}
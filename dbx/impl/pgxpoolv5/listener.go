@@ -0,0 +1,211 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	DefaultListenerCheckInterval = time.Second
+	DefaultListenerBaseBackoff   = 100 * time.Millisecond
+	DefaultListenerMaxBackoff    = 10 * time.Second
+)
+
+// NotificationHandler handles notification, received by Listener.
+type NotificationHandler func(ctx context.Context, n *pgconn.Notification)
+
+// Listener holds dedicated connection on the current primary, LISTENs on channels
+// and delivers notifications to handlers. When connection is lost or primary changes, Listener
+// reconnects to the new primary and LISTENs again. Notifications, sent while Listener is reconnecting,
+// are lost, so handlers should be able to catch up by other means.
+type Listener struct {
+	db *DB
+
+	mu       sync.Mutex
+	handlers map[string][]NotificationHandler
+
+	checkInterval time.Duration
+	backoff       func(attempt int) time.Duration
+	onError       func(err error)
+
+	connect func(ctx context.Context, node cluster.Node[*pgxpool.Pool]) (listenerConn, error)
+}
+
+// listenerConn is a part of *pgx.Conn, used by Listener.
+type listenerConn interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	WaitForNotification(ctx context.Context) (*pgconn.Notification, error)
+	Close(ctx context.Context) error
+}
+
+type ListenerOption func(l *Listener)
+
+// WithListenerCheckInterval sets how often Listener checks, that its node is still primary
+// and LISTENs on newly added channels.
+func WithListenerCheckInterval(interval time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.checkInterval = interval
+	}
+}
+
+// WithListenerBackoff sets delay before reconnect attempts.
+func WithListenerBackoff(backoff func(attempt int) time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.backoff = backoff
+	}
+}
+
+// WithListenerErrorHandler sets function, which is called with errors, caused reconnects.
+func WithListenerErrorHandler(onError func(err error)) ListenerOption {
+	return func(l *Listener) {
+		l.onError = onError
+	}
+}
+
+// NewListener returns Listener, bound to db cluster. Call Run to start listening.
+func (db *DB) NewListener(options ...ListenerOption) *Listener {
+	l := &Listener{
+		db:            db,
+		handlers:      make(map[string][]NotificationHandler),
+		checkInterval: DefaultListenerCheckInterval,
+		backoff:       dbx.ExponentialBackoff(DefaultListenerBaseBackoff, DefaultListenerMaxBackoff),
+		onError:       func(err error) {},
+		connect:       hijackConn,
+	}
+
+	for _, opt := range options {
+		opt(l)
+	}
+
+	return l
+}
+
+// Handle registers handler for channel. Channels, registered while Listener is running,
+// are LISTENed within check interval.
+func (l *Listener) Handle(channel string, handler NotificationHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.handlers[channel] = append(l.handlers[channel], handler)
+}
+
+// Run listens until ctx is done. Handlers are called sequentially from Run goroutine.
+func (l *Listener) Run(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := l.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		l.onError(err)
+
+		// Session, which lived long enough, resets backoff.
+		if time.Since(start) > l.checkInterval {
+			attempt = 1
+		}
+
+		timer := time.NewTimer(l.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (l *Listener) session(ctx context.Context) error {
+	node, err := l.db.GetNode(ctx, dbx.WaitForPrimary())
+	if err != nil {
+		return errx.Wrap("wait for primary", err)
+	}
+
+	conn, err := l.connect(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	listened := make(map[string]struct{})
+	for {
+		if err := l.listen(ctx, conn, listened); err != nil {
+			return err
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, l.checkInterval)
+		n, err := conn.WaitForNotification(waitCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) {
+				if !l.db.Cluster.IsPrimary(node) {
+					return dbx.ErrPrimaryChanged
+				}
+				continue
+			}
+
+			return errx.Wrap("wait for notification", err)
+		}
+
+		l.mu.Lock()
+		handlers := l.handlers[n.Channel]
+		l.mu.Unlock()
+
+		for _, handler := range handlers {
+			handler(ctx, n)
+		}
+	}
+}
+
+// hijackConn acquires connection from node pool and takes it over, as connection with LISTENs
+// must not be returned to pool.
+func hijackConn(ctx context.Context, node cluster.Node[*pgxpool.Pool]) (listenerConn, error) {
+	poolConn, err := node.DB().Acquire(ctx)
+	if err != nil {
+		return nil, errx.Wrap("acquire conn", err)
+	}
+
+	return poolConn.Hijack(), nil
+}
+
+func (l *Listener) listen(ctx context.Context, conn listenerConn, listened map[string]struct{}) error {
+	l.mu.Lock()
+	var channels []string
+	for channel := range l.handlers {
+		if _, ok := listened[channel]; !ok {
+			channels = append(channels, channel)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return errx.Wrap("listen", err)
+		}
+
+		listened[channel] = struct{}{}
+	}
+
+	return nil
+}
+
+// Notify sends notification to channel. It is routed to WriteToNodeStrategy node or sent
+// in transaction, if db or ctx is bound to one, so it is delivered on commit.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	_, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return errx.Wrap("notify", err)
+}
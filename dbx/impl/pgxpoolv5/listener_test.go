@@ -0,0 +1,167 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeListenerConn struct {
+	addr          string
+	listens       chan string
+	notifications chan *pgconn.Notification
+	failures      chan error
+}
+
+func newFakeListenerConn(addr string) *fakeListenerConn {
+	return &fakeListenerConn{
+		addr:          addr,
+		listens:       make(chan string, 10),
+		notifications: make(chan *pgconn.Notification),
+		failures:      make(chan error),
+	}
+}
+
+func (c *fakeListenerConn) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	if strings.HasPrefix(sql, "LISTEN ") {
+		c.listens <- strings.TrimPrefix(sql, "LISTEN ")
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (c *fakeListenerConn) WaitForNotification(ctx context.Context) (*pgconn.Notification, error) {
+	select {
+	case n := <-c.notifications:
+		return n, nil
+	case err := <-c.failures:
+		return nil, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakeListenerConn) Close(ctx context.Context) error {
+	return nil
+}
+
+// newSwitchableDB returns db over fake pools, where primary is the node with address, stored in primary.
+func newSwitchableDB(t *testing.T, primary *atomic.Value, addrs ...string) *DB {
+	pools := make(map[*pgxpool.Pool]string)
+	var mu sync.Mutex
+
+	dsns := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		dsns = append(dsns, "postgres://"+addr+"/db")
+	}
+
+	db, err := NewDB(dsns,
+		WithPoolOpener(func(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			pool := &pgxpool.Pool{}
+			pools[pool] = strings.TrimSuffix(strings.TrimPrefix(dsn, "postgres://"), "/db")
+			return pool, nil
+		}),
+		WithPoolCloser(func(*pgxpool.Pool) error { return nil }),
+		WithNodeChecker(func(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return pools[pool] == primary.Load().(string), nil
+		}),
+		WithGenericOptions(
+			dbx.WithNodeWaitTimeout[*pgxpool.Pool](time.Second),
+			dbx.WithClusterOptions(cluster.WithUpdateInterval[*pgxpool.Pool](10*time.Millisecond)),
+		),
+	)
+	assert.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		var zero T
+		return zero
+	}
+}
+
+func TestListenerReconnects(t *testing.T) {
+	var primary atomic.Value
+	primary.Store("a")
+	db := newSwitchableDB(t, &primary, "a", "b")
+
+	conns := make(chan *fakeListenerConn, 10)
+	errs := make(chan error, 10)
+	payloads := make(chan string, 10)
+
+	l := db.NewListener(
+		WithListenerCheckInterval(10*time.Millisecond),
+		WithListenerBackoff(func(attempt int) time.Duration { return time.Millisecond }),
+		WithListenerErrorHandler(func(err error) { errs <- err }),
+	)
+	l.connect = func(ctx context.Context, node cluster.Node[*pgxpool.Pool]) (listenerConn, error) {
+		conn := newFakeListenerConn(node.Addr())
+		conns <- conn
+		return conn, nil
+	}
+	l.Handle("events", func(ctx context.Context, n *pgconn.Notification) {
+		payloads <- n.Payload
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- l.Run(ctx) }()
+
+	first := receive(t, conns)
+	assert.Equal(t, "a", first.addr)
+	assert.Equal(t, `"events"`, receive(t, first.listens))
+
+	first.notifications <- &pgconn.Notification{Channel: "events", Payload: "1"}
+	assert.Equal(t, "1", receive(t, payloads))
+
+	t.Run("primary changed", func(t *testing.T) {
+		primary.Store("b")
+
+		second := receive(t, conns)
+		assert.ErrorIs(t, receive(t, errs), dbx.ErrPrimaryChanged)
+		assert.Equal(t, "b", second.addr)
+		assert.Equal(t, `"events"`, receive(t, second.listens))
+
+		second.notifications <- &pgconn.Notification{Channel: "events", Payload: "2"}
+		assert.Equal(t, "2", receive(t, payloads))
+
+		t.Run("conn lost", func(t *testing.T) {
+			second.failures <- io.ErrUnexpectedEOF
+
+			third := receive(t, conns)
+			assert.ErrorIs(t, receive(t, errs), io.ErrUnexpectedEOF)
+			assert.Equal(t, "b", third.addr)
+			assert.Equal(t, `"events"`, receive(t, third.listens))
+
+			third.notifications <- &pgconn.Notification{Channel: "events", Payload: "3"}
+			assert.Equal(t, "3", receive(t, payloads))
+		})
+	})
+
+	cancel()
+	assert.ErrorIs(t, receive(t, done), context.Canceled)
+}
@@ -0,0 +1,48 @@
+package pgxpoolv5
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// BindNamed rewrites `:name` and `@name` placeholders in sql to `$n` ones.
+// Arguments are taken from map or tagged struct.
+func (db *DB) BindNamed(sql string, arg any) (string, []any, error) {
+	return dbx.BindNamed(sql, arg, dbx.PlaceholderDollar)
+}
+
+// NamedExec executes query with named arguments.
+func (db *DB) NamedExec(ctx context.Context, sql string, arg any) (pgconn.CommandTag, error) {
+	sql, args, err := db.BindNamed(sql, arg)
+	if err != nil {
+		return pgconn.CommandTag{}, errx.Wrap("bind named", err)
+	}
+
+	return db.Exec(ctx, sql, args...)
+}
+
+// NamedQuery queries underlying cluster with named arguments.
+func (db *DB) NamedQuery(ctx context.Context, sql string, arg any) (pgx.Rows, error) {
+	sql, args, err := db.BindNamed(sql, arg)
+	if err != nil {
+		return nil, errx.Wrap("bind named", err)
+	}
+
+	return db.Query(ctx, sql, args...)
+}
+
+// NamedQueryRow queries row from underlying cluster with named arguments.
+func (db *DB) NamedQueryRow(ctx context.Context, sql string, arg any) pgx.Row {
+	sql, args, err := db.BindNamed(sql, arg)
+	if err != nil {
+		return &errRow{
+			err: errx.Wrap("bind named", err),
+		}
+	}
+
+	return db.QueryRow(ctx, sql, args...)
+}
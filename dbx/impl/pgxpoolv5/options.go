@@ -1,11 +1,16 @@
 package pgxpoolv5
 
 import (
+	"context"
+
 	"github.com/ValerySidorin/corex/dbx"
 	"github.com/ValerySidorin/corex/dbx/cluster"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// StartupHook is called at the end of NewDB, e.g. to migrate database before serving.
+type StartupHook func(ctx context.Context, db *DB) error
+
 type Option func(*DB)
 
 func WithGenericOptions(options ...dbx.Option[*pgxpool.Pool]) Option {
@@ -31,3 +36,10 @@ func WithPoolCloser(poolCloser cluster.ConnCloser[*pgxpool.Pool]) Option {
 		db.poolCloser = poolCloser
 	}
 }
+
+// WithStartupHook adds hook, which is called at the end of NewDB. NewDB closes cluster and fails, if hook fails.
+func WithStartupHook(hook StartupHook) Option {
+	return func(db *DB) {
+		db.startupHooks = append(db.startupHooks, hook)
+	}
+}
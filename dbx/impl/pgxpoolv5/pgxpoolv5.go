@@ -3,7 +3,6 @@ package pgxpoolv5
 import (
 	"context"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/ValerySidorin/corex/dbx"
@@ -20,13 +19,15 @@ const DefaultPingTimeout = 15 * time.Second
 
 type DB struct {
 	*dbx.DB[*pgxpool.Pool]
-	genericOpts []dbx.Option[*pgxpool.Pool]
+	genericOpts  []dbx.Option[*pgxpool.Pool]
+	startupHooks []StartupHook
 
 	poolOpener  PoolOpener
 	poolCloser  cluster.ConnCloser[*pgxpool.Pool]
 	nodeChecker cluster.NodeChecker[*pgxpool.Pool]
 
-	tx pgx.Tx
+	tx   pgx.Tx
+	conn *pgxpool.Conn
 }
 
 func NewDB(dsns []string, options ...Option) (*DB, error) {
@@ -36,6 +37,13 @@ func NewDB(dsns []string, options ...Option) (*DB, error) {
 		opt(resDB)
 	}
 
+	genericOpts := append([]dbx.Option[*pgxpool.Pool]{
+		dbx.WithReadRetryPolicy[*pgxpool.Pool](dbx.RetryPolicy{
+			MaxAttempts: dbx.DefaultReadRetryMaxAttempts,
+			Retryable:   IsConnError,
+		}),
+	}, resDB.genericOpts...)
+
 	var err error
 	resDB.DB, err = dbx.NewDB("pgx", dsns,
 		func(ctx context.Context, driverName, dsn string) (*pgxpool.Pool, error) {
@@ -48,10 +56,21 @@ func NewDB(dsns []string, options ...Option) (*DB, error) {
 		},
 		resDB.poolCloser,
 		resDB.nodeChecker,
-		resDB.genericOpts...,
+		genericOpts...,
 	)
 
-	return resDB, errx.Wrap("init generic db", err)
+	if err != nil {
+		return resDB, errx.Wrap("init generic db", err)
+	}
+
+	for _, hook := range resDB.startupHooks {
+		if err := hook(resDB.Ctx, resDB); err != nil {
+			resDB.Close()
+			return resDB, errx.Wrap("run startup hook", err)
+		}
+	}
+
+	return resDB, nil
 }
 
 func (db *DB) WithCtx(ctx context.Context) *DB {
@@ -84,27 +103,42 @@ func (db *DB) WithDefaultNodeStrategy(strategy dbx.GetNodeStragegy) *DB {
 	return resDB
 }
 
-func (db *DB) DoTx(f func(db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error, opts pgx.TxOptions) error {
-	newDB, err := db.withTx(context.Background(), opts)
-	if err != nil {
-		return errx.Wrap("with tx", err)
-	}
-	defer func() {
-		_ = newDB.tx.Rollback(context.Background())
-	}()
+func (db *DB) WithReadRetryPolicy(policy dbx.RetryPolicy) *DB {
+	resDB := db.copy()
+	resDB.ReadRetryPolicy = policy
+	return resDB
+}
 
-	err = f(newDB)
-	if err != nil {
-		return errx.Wrap("exec func in tx", err)
-	}
+func (db *DB) WithTxRetryPolicy(policy dbx.TxRetryPolicy) *DB {
+	resDB := db.copy()
+	resDB.TxRetryPolicy = policy
+	return resDB
+}
 
-	if err := newDB.tx.Commit(context.Background()); err != nil {
-		return errx.Wrap("commit", err)
-	}
+func (db *DB) WithStructScanMode(mode dbx.StructScanMode) *DB {
+	resDB := db.copy()
+	resDB.StructScanMode = mode
+	return resDB
+}
 
-	return nil
+// WithHedgedReads returns copy of db, which hedges reads with policy, e.g. for latency-sensitive endpoints.
+func (db *DB) WithHedgedReads(policy dbx.HedgePolicy) *DB {
+	resDB := db.copy()
+	resDB.Hedger = dbx.NewHedger(policy)
+	return resDB
+}
+
+// DoTx executes passed function in transaction.
+// Nested transactions are emulated with savepoints.
+func (db *DB) DoTx(f func(db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error, opts pgx.TxOptions) error {
+	return db.DoTxContext(context.Background(),
+		func(ctx context.Context, db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error {
+			return f(db)
+		}, opts)
 }
 
+// DoTxContext executes passed function in transaction.
+// Nested transactions are emulated with savepoints.
 func (db *DB) DoTxContext(
 	ctx context.Context,
 	f func(ctx context.Context, db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error,
@@ -113,22 +147,68 @@ func (db *DB) DoTxContext(
 	if err != nil {
 		return errx.Wrap("with tx", err)
 	}
+
+	var committed bool
 	defer func() {
-		_ = newDB.tx.Rollback(ctx)
+		if !committed {
+			_ = newDB.tx.Rollback(ctx)
+			newDB.TxHooks.RolledBack(ctx, err)
+		}
 	}()
 
-	err = f(ctx, newDB)
+	err = f(newDB.Ctx, newDB)
 	if err != nil {
 		return errx.Wrap("exec func in tx", err)
 	}
 
+	committed = true
 	if err := newDB.tx.Commit(ctx); err != nil {
-		return errx.Wrap("commit", err)
+		newDB.TxHooks.RolledBack(ctx, err)
+		return errx.Wrap("commit", &dbx.CommitError{Err: err})
 	}
 
+	newDB.TxHooks.Committed(ctx)
 	return nil
 }
 
+// DoInTx executes passed function in transaction. Transaction is propagated through context,
+// so all *DB methods, called with this context, join it.
+func (db *DB) DoInTx(ctx context.Context, f func(ctx context.Context) error, opts pgx.TxOptions) error {
+	return db.DoTxContext(ctx,
+		func(ctx context.Context, _ dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error {
+			return f(ctx)
+		}, opts)
+}
+
+// DoRetryTx executes passed function in transaction and retries the whole transaction on another
+// primary according to TxRetryPolicy.
+func (db *DB) DoRetryTx(f func(db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error, opts pgx.TxOptions) error {
+	return db.DoRetryTxContext(context.Background(),
+		func(ctx context.Context, db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error {
+			return f(db)
+		}, opts)
+}
+
+// DoRetryTxContext executes passed function in transaction and retries the whole transaction on another
+// primary according to TxRetryPolicy. Nested transactions are not retried.
+func (db *DB) DoRetryTxContext(
+	ctx context.Context,
+	f func(ctx context.Context, db dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions]) error,
+	opts pgx.TxOptions) error {
+	if db.joinTx(ctx).tx != nil {
+		return db.DoTxContext(ctx, f, opts)
+	}
+
+	return db.RetryTx(ctx, func(ctx context.Context) error {
+		return db.DoTxContext(ctx, f, opts)
+	})
+}
+
+// Dialect returns SQL dialect of db.
+func (db *DB) Dialect() dbx.Dialect {
+	return dbx.DialectPostgres
+}
+
 func (db *DB) Tx() (pgx.Tx, error) {
 	if db.tx == nil {
 		return nil, errors.New("no pgx tx")
@@ -138,116 +218,185 @@ func (db *DB) Tx() (pgx.Tx, error) {
 }
 
 func (db *DB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.Exec(ctx, sql, arguments...)
 		return res, errx.Wrap("exec in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.Exec(ctx, sql, arguments...)
+		return res, errx.Wrap("exec on conn", err)
+	}
+
 	pool, err := db.GetWriteToConn(ctx)
 	if err != nil {
 		return pgconn.CommandTag{}, errx.Wrap("wait for write to conn", err)
 	}
 
-	res, err := pool.Exec(ctx, sql, arguments...)
+	res, err := db.exec(ctx, pool, sql, arguments...)
 	return res, errx.Wrap("exec", err)
 }
 
+// ExecExpect executes query and returns *dbx.RowsAffectedError, if it affected
+// other than expected number of rows.
+func (db *DB) ExecExpect(ctx context.Context, expected int64, sql string, arguments ...any) error {
+	tag, err := db.Exec(ctx, sql, arguments...)
+	if err != nil {
+		return err
+	}
+
+	return dbx.ExpectRowsAffected(&commandTagResult{tag: tag}, expected)
+}
+
+// Query queries underlying cluster. Reads, that failed with connection-level error,
+// are retried on another node according to ReadRetryPolicy. If Hedger is set, slow reads are hedged to another node.
+// Locking reads and queries with side effects (e.g. DML with RETURNING) are sent to WriteToNodeStrategy node
+// and are not retried.
 func (db *DB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.Query(ctx, sql, args...)
 		return res, errx.Wrap("query in tx", err)
 	}
 
-	var (
-		pool *pgxpool.Pool
-		err  error
-	)
+	if db.conn != nil {
+		res, err := db.conn.Query(ctx, sql, args...)
+		return res, errx.Wrap("query on conn", err)
+	}
 
-	if isSelectWithLock(sql) {
-		pool, err = db.GetWriteToConn(ctx)
-	} else {
-		pool, err = db.GetReadFromConn(ctx)
+	if !dbx.IsReadOnlyQuery(sql) {
+		pool, err := db.GetWriteToConn(ctx)
+		if err != nil {
+			return nil, errx.Wrap("wait for conn", err)
+		}
+
+		res, err := db.query(ctx, pool, sql, args...)
+		return res, errx.Wrap("query", err)
 	}
+
+	rows, release, err := dbx.HedgedRead(ctx, db.DB, sql, func(ctx context.Context, pool *pgxpool.Pool) (pgx.Rows, error) {
+		res, err := db.query(ctx, pool, sql, args...)
+		return res, errx.Wrap("query", err)
+	}, func(rows pgx.Rows) {
+		rows.Close()
+	})
 	if err != nil {
-		return nil, errx.Wrap("wait for conn", err)
+		return nil, err
 	}
 
-	res, err := pool.Query(ctx, sql, args...)
-	return res, errx.Wrap("query", err)
+	// Context of winning read is released, when rows are closed.
+	return &releaseRows{Rows: rows, release: release}, nil
 }
 
+// QueryRow queries row from underlying cluster. Reads, that failed with connection-level error,
+// are retried on another node according to ReadRetryPolicy. As with pgx, errors are deferred until Scan.
+// Locking reads and queries with side effects are routed as in Query.
 func (db *DB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		return db.tx.QueryRow(ctx, sql, args...)
 	}
 
-	var (
-		pool *pgxpool.Pool
-		err  error
-	)
-
-	if isSelectWithLock(sql) {
-		pool, err = db.GetWriteToConn(ctx)
-	} else {
-		pool, err = db.GetReadFromConn(ctx)
+	if db.conn != nil {
+		return db.conn.QueryRow(ctx, sql, args...)
 	}
-	if err != nil {
-		return &errRow{
-			err: errx.Wrap("wait for read from conn", err),
+
+	if !dbx.IsReadOnlyQuery(sql) {
+		pool, err := db.GetWriteToConn(ctx)
+		if err != nil {
+			return &errRow{
+				err: errx.Wrap("wait for write to conn", err),
+			}
 		}
+
+		return db.queryRow(ctx, pool, sql, args...)
 	}
 
-	return pool.QueryRow(ctx, sql, args...)
+	return &retryRow{
+		db:   db,
+		ctx:  ctx,
+		sql:  sql,
+		args: args,
+	}
 }
 
+// SendBatch sends batch to underlying cluster. Batches of reads without locks and side effects are sent to
+// ReadFromNodeStrategy node, batches with any write are sent to WriteToNodeStrategy node.
 func (db *DB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		return db.tx.SendBatch(ctx, b)
 	}
 
-	pool, err := db.GetDefaultConn(ctx)
+	if db.conn != nil {
+		return db.conn.SendBatch(ctx, b)
+	}
+
+	getConn := db.GetWriteToConn
+	if isReadOnlyBatch(b) {
+		getConn = db.GetReadFromConn
+	}
+
+	pool, err := getConn(ctx)
 	if err != nil {
 		return &errBatchResults{
-			err: errx.Wrap("wait for default conn", err),
+			err: errx.Wrap("wait for conn", err),
 		}
 	}
 
-	return pool.SendBatch(ctx, b)
+	return db.sendBatch(ctx, pool, b)
 }
 
 func (db *DB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
 		return res, errx.Wrap("copy from in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+		return res, errx.Wrap("copy from on conn", err)
+	}
+
 	pool, err := db.GetWriteToConn(ctx)
 	if err != nil {
 		return 0, errx.Wrap("wait for write to conn", err)
 	}
 
-	res, err := pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	res, err := db.copyFrom(ctx, pool, tableName, columnNames, rowSrc)
 	return res, errx.Wrap("copy from", err)
 }
 
+// ErrNotPinned is returned by Prepare, when neither db nor ctx is bound to transaction or pinned connection.
+var ErrNotPinned = errors.New("prepare requires transaction or pinned connection")
+
+// Prepare prepares statement on transaction or pinned connection. Prepared statements are
+// connection-scoped, so it must be called within transaction or WithConn, otherwise ErrNotPinned is returned.
+//
+// Breaking change: Prepare used to prepare statement on connection, acquired from DefaultNodeStrategy node,
+// and never returned it to pool. Statement was not visible to later calls, which run on other connections,
+// so such callers must move Prepare and statement executions into WithConn or transaction.
 func (db *DB) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.Prepare(ctx, name, sql)
 		return res, errx.Wrap("prepare in tx", err)
 	}
 
-	pool, err := db.GetDefaultConn(ctx)
-	if err != nil {
-		return nil, errx.Wrap("wait for write to conn", err)
-	}
-
-	conn, err := pool.Acquire(ctx)
-	if err != nil {
-		return nil, errx.Wrap("acquire conn", err)
+	if db.conn != nil {
+		res, err := db.conn.Conn().Prepare(ctx, name, sql)
+		return res, errx.Wrap("prepare on conn", err)
 	}
 
-	res, err := conn.Conn().Prepare(ctx, name, sql)
-	return res, errx.Wrap("prepare", err)
+	return nil, ErrNotPinned
 }
 
 func newDB() *DB {
@@ -265,18 +414,33 @@ func (db *DB) withTx(ctx context.Context, opts pgx.TxOptions) (*DB, error) {
 		err  error
 	)
 
-	newDB := db.copy()
-	newDB.Ctx = ctx
+	newDB := db.joinTx(ctx).copy()
+	newDB.Ctx = newDB.ContextWithTx(ctx, newDB)
 
 	if newDB.tx != nil {
-		txConn := newDB.tx.Conn()
-
-		tx, err := txConn.BeginTx(ctx, opts)
+		tx, err := newDB.tx.Begin(ctx)
 		if err != nil {
 			return nil, errx.Wrap("begin nested tx", err)
 		}
 
 		newDB.tx = tx
+		newDB.TxHooks = newDB.TxHooks.Nested()
+		return newDB, nil
+	}
+
+	if newDB.conn != nil {
+		tx, err := newDB.conn.BeginTx(newDB.Ctx, opts)
+		if err != nil {
+			return nil, errx.Wrap("begin tx on conn", err)
+		}
+
+		if err := newDB.applyTenantScope(ctx, tx.Exec, true); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, err
+		}
+
+		newDB.tx = tx
+		newDB.TxHooks = &dbx.TxHooks{}
 		return newDB, nil
 	}
 
@@ -295,10 +459,42 @@ func (db *DB) withTx(ctx context.Context, opts pgx.TxOptions) (*DB, error) {
 		return nil, errx.Wrap("begin tx", err)
 	}
 
+	if err := newDB.applyTenantScope(ctx, tx.Exec, true); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+
 	newDB.tx = tx
+	newDB.TxHooks = &dbx.TxHooks{}
 	return newDB, nil
 }
 
+// joinTx returns *DB bound to transaction from ctx, if db itself is not bound to transaction.
+// Otherwise it returns *DB bound to pinned connection from ctx, if db itself is not bound to one.
+func (db *DB) joinTx(ctx context.Context) *DB {
+	if db.tx != nil {
+		return db
+	}
+
+	if tx, ok := db.TxFromContext(ctx); ok {
+		if txDB, ok := tx.(*DB); ok {
+			return txDB
+		}
+	}
+
+	if db.conn != nil {
+		return db
+	}
+
+	if conn, ok := db.ConnFromContext(ctx); ok {
+		if connDB, ok := conn.(*DB); ok {
+			return connDB
+		}
+	}
+
+	return db
+}
+
 func (db *DB) copy() *DB {
 	return &DB{
 		DB:          db.DB.Copy(),
@@ -307,6 +503,7 @@ func (db *DB) copy() *DB {
 		poolCloser:  db.poolCloser,
 		nodeChecker: db.nodeChecker,
 		tx:          db.tx,
+		conn:        db.conn,
 	}
 }
 
@@ -314,10 +511,12 @@ func _() dbx.DBxer[*pgxpool.Pool, pgx.Tx, pgx.TxOptions] {
 	return &DB{}
 }
 
-func isSelectWithLock(sql string) bool {
-	sql = strings.ToLower(sql)
-	return strings.Contains(sql, "for update") ||
-		strings.Contains(sql, "for no key update") ||
-		strings.Contains(sql, "for share") ||
-		strings.Contains(sql, "for key share")
+func _() dbx.TxManager[pgx.TxOptions] {
+	return &DB{}
+}
+
+// IsConnError reports whether err is a connection-level error, including pgconn errors,
+// which are known to be safe to retry.
+func IsConnError(err error) bool {
+	return dbx.IsConnError(err) || pgconn.SafeToRetry(err)
 }
@@ -0,0 +1,112 @@
+package pgxpoolv5
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type querier struct {
+	db *DB
+}
+
+var _ dbx.Querier = &querier{}
+
+// Querier returns driver-agnostic query API of db.
+func (db *DB) Querier() dbx.Querier {
+	return &querier{db: db}
+}
+
+func (q *querier) ExecContext(ctx context.Context, query string, args ...any) (dbx.Result, error) {
+	tag, err := q.db.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap("querier exec", err)
+	}
+
+	return &commandTagResult{tag: tag}, nil
+}
+
+func (q *querier) QueryContext(ctx context.Context, query string, args ...any) (dbx.QueryRows, error) {
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap("querier query", err)
+	}
+
+	return &queryRows{Rows: rows}, nil
+}
+
+// QueryRowContext queries the first row at once, so query errors are returned by Err as well as by Scan.
+func (q *querier) QueryRowContext(ctx context.Context, query string, args ...any) dbx.Row {
+	rows, err := q.db.Query(ctx, query, args...)
+	if err != nil {
+		return &queryRow{err: errx.Wrap("querier query row", err)}
+	}
+
+	row := &queryRow{rows: rows, next: rows.Next()}
+	if !row.next {
+		rows.Close()
+		row.err = errx.Wrap("querier query row", rows.Err())
+	}
+
+	return row
+}
+
+type commandTagResult struct {
+	tag pgconn.CommandTag
+}
+
+func (r *commandTagResult) RowsAffected() (int64, error) {
+	return r.tag.RowsAffected(), nil
+}
+
+type queryRows struct {
+	pgx.Rows
+}
+
+func (r *queryRows) Columns() ([]string, error) {
+	fields := r.FieldDescriptions()
+	res := make([]string, 0, len(fields))
+	for _, field := range fields {
+		res = append(res, field.Name)
+	}
+
+	return res, nil
+}
+
+func (r *queryRows) Close() error {
+	r.Rows.Close()
+	return r.Rows.Err()
+}
+
+// queryRow is a row, which behaves as *sql.Row: Err returns query error and Scan returns error,
+// wrapping both dbx.ErrNotFound and pgx.ErrNoRows, if there are no rows.
+type queryRow struct {
+	rows pgx.Rows
+	next bool
+	err  error
+}
+
+func (r *queryRow) Err() error {
+	return r.err
+}
+
+func (r *queryRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if !r.next {
+		return dbx.NotFound(pgx.ErrNoRows)
+	}
+	defer r.rows.Close()
+
+	if err := r.rows.Scan(dest...); err != nil {
+		return errx.Wrap("scan", err)
+	}
+
+	r.rows.Close()
+	return errx.Wrap("close rows", r.rows.Err())
+}
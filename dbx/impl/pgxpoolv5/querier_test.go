@@ -0,0 +1,29 @@
+package pgxpoolv5
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuerierNotFound(t *testing.T) {
+	srv := newFakePG(t, func(sql string) fakeResult {
+		if sql == "SELECT name FROM foo WHERE id = 2" {
+			return fakeResult{columns: []string{"name"}, rows: [][]any{{"bar"}}}
+		}
+		return fakeResult{columns: []string{"name"}}
+	})
+	q := newFakeDB(t, srv).Querier()
+	ctx := context.Background()
+
+	var name string
+	assert.Nil(t, q.QueryRowContext(ctx, "SELECT name FROM foo WHERE id = 2").Scan(&name))
+	assert.Equal(t, "bar", name)
+
+	err := q.QueryRowContext(ctx, "SELECT name FROM foo WHERE id = 3").Scan(&name)
+	assert.ErrorIs(t, err, dbx.ErrNotFound)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,14 @@
+package pgxpoolv5
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx"
+)
+
+// RegistryOpener returns dbx.Registry opener, which opens tenant *DB with options.
+func RegistryOpener(options ...Option) dbx.RegistryOpener[*DB] {
+	return func(ctx context.Context, tenantID string, dsns []string) (*DB, error) {
+		return NewDB(dsns, options...)
+	}
+}
@@ -0,0 +1,147 @@
+package pgxpoolv5
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (db *DB) WithTenantScope(scope *dbx.TenantScope) *DB {
+	resDB := db.copy()
+	resDB.TenantScope = scope
+	return resDB
+}
+
+// applyTenantScope applies tenant scope of ctx to session or, if local is true, to transaction.
+func (db *DB) applyTenantScope(ctx context.Context,
+	exec func(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error), local bool) error {
+	if db.TenantScope == nil {
+		return nil
+	}
+
+	sql, args, err := db.TenantScope.Query(ctx, local)
+	if err != nil || sql == "" {
+		return err
+	}
+
+	_, err = exec(ctx, sql, args...)
+	return errx.Wrap("apply tenant scope", err)
+}
+
+// checkout acquires connection from pool and applies tenant scope of ctx to it.
+func (db *DB) checkout(ctx context.Context, pool *pgxpool.Pool) (*pgxpool.Conn, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, errx.Wrap("acquire conn", err)
+	}
+
+	if err := db.applyTenantScope(ctx, conn.Exec, false); err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// release resets tenant settings of conn, checked out by checkout, and returns it to pool.
+// Connection, which is left in transaction or failed to reset, is closed instead.
+func (db *DB) release(ctx context.Context, conn *pgxpool.Conn) {
+	if conn.Conn().PgConn().TxStatus() != 'I' {
+		_ = conn.Hijack().Close(context.Background())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultSessionResetTimeout)
+	defer cancel()
+
+	sql, args := db.TenantScope.ResetQuery()
+	if sql != "" {
+		if _, err := conn.Exec(ctx, sql, args...); err != nil {
+			_ = conn.Hijack().Close(context.Background())
+			return
+		}
+	}
+
+	conn.Release()
+}
+
+// The following methods execute statements on pool. If tenant scope is set, statements are executed
+// on checked out connection, which is released, when statement results are consumed.
+
+func (db *DB) exec(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) (pgconn.CommandTag, error) {
+	if db.TenantScope == nil {
+		return pool.Exec(ctx, sql, args...)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer db.release(ctx, conn)
+
+	return conn.Exec(ctx, sql, args...)
+}
+
+func (db *DB) query(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) (pgx.Rows, error) {
+	if db.TenantScope == nil {
+		return pool.Query(ctx, sql, args...)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		db.release(ctx, conn)
+		return nil, err
+	}
+
+	return &releaseRows{Rows: rows, release: func() { db.release(ctx, conn) }}, nil
+}
+
+func (db *DB) queryRow(ctx context.Context, pool *pgxpool.Pool, sql string, args ...any) pgx.Row {
+	if db.TenantScope == nil {
+		return pool.QueryRow(ctx, sql, args...)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return &errRow{err: err}
+	}
+
+	return &releaseRow{row: conn.QueryRow(ctx, sql, args...), release: func() { db.release(ctx, conn) }}
+}
+
+func (db *DB) sendBatch(ctx context.Context, pool *pgxpool.Pool, b *pgx.Batch) pgx.BatchResults {
+	if db.TenantScope == nil {
+		return pool.SendBatch(ctx, b)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return &errBatchResults{err: err}
+	}
+
+	return &releaseBatchResults{BatchResults: conn.SendBatch(ctx, b), release: func() { db.release(ctx, conn) }}
+}
+
+func (db *DB) copyFrom(ctx context.Context, pool *pgxpool.Pool,
+	tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if db.TenantScope == nil {
+		return pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	}
+
+	conn, err := db.checkout(ctx, pool)
+	if err != nil {
+		return 0, err
+	}
+	defer db.release(ctx, conn)
+
+	return conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// AdvisoryLock is a session-scoped Postgres advisory lock. It keeps its connection pinned
+// until released.
+type AdvisoryLock struct {
+	db   *DB
+	node cluster.Node[*sql.DB]
+	conn *sql.Conn
+	key  int64
+}
+
+var _ dbx.AdvisoryLock = &AdvisoryLock{}
+
+// AcquireLock waits for session-scoped advisory lock on the primary. Supported only by Postgres drivers.
+func (db *DB) AcquireLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	lock, _, err := db.acquireLock(ctx, key, "SELECT true FROM pg_advisory_lock($1)")
+	return lock, err
+}
+
+// TryAcquireLock tries to acquire session-scoped advisory lock on the primary without waiting.
+// ok is false, if lock is held by another session. Supported only by Postgres drivers.
+func (db *DB) TryAcquireLock(ctx context.Context, key int64) (*AdvisoryLock, bool, error) {
+	return db.acquireLock(ctx, key, "SELECT pg_try_advisory_lock($1)")
+}
+
+func (db *DB) acquireLock(ctx context.Context, key int64, query string) (*AdvisoryLock, bool, error) {
+	if err := db.checkAdvisoryLocks(); err != nil {
+		return nil, false, err
+	}
+
+	node, err := db.GetNode(ctx, dbx.WaitForPrimary())
+	if err != nil {
+		return nil, false, errx.Wrap("wait for primary", err)
+	}
+
+	conn, err := node.DB().Conn(ctx)
+	if err != nil {
+		return nil, false, errx.Wrap("get conn", err)
+	}
+
+	var ok bool
+	if err := conn.QueryRowContext(ctx, query, key).Scan(&ok); err != nil {
+		// Lock may be acquired right before error, so connection is discarded to release it.
+		discardConn(conn)
+		return nil, false, errx.Wrap("lock", err)
+	}
+
+	if !ok {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	return &AdvisoryLock{
+		db:   db,
+		node: node,
+		conn: conn,
+		key:  key,
+	}, true, nil
+}
+
+// Check returns error, if lock connection is broken or its node is no longer primary.
+func (l *AdvisoryLock) Check(ctx context.Context) error {
+	if !l.db.Cluster.IsPrimary(l.node) {
+		return dbx.ErrPrimaryChanged
+	}
+
+	return errx.Wrap("ping", l.conn.PingContext(ctx))
+}
+
+// Release releases lock and returns its connection to pool. If unlock fails,
+// connection is discarded, so the lock is released with the session.
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	var unlocked bool
+	if err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", l.key).Scan(&unlocked); err != nil {
+		discardConn(l.conn)
+		return errx.Wrap("unlock", err)
+	}
+
+	_ = l.conn.Close()
+	if !unlocked {
+		return errors.New("lock was not held")
+	}
+
+	return nil
+}
+
+// XactLock waits for transaction-scoped advisory lock. It is released at the end of transaction,
+// which db or ctx must be bound to. Supported only by Postgres drivers.
+func (db *DB) XactLock(ctx context.Context, key int64) error {
+	if err := db.checkAdvisoryLocks(); err != nil {
+		return err
+	}
+
+	db = db.joinTx(ctx)
+	if db.tx == nil {
+		return errors.New("xact lock requires transaction")
+	}
+
+	_, err := db.tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", key)
+	return errx.Wrap("xact lock", err)
+}
+
+// TryXactLock tries to acquire transaction-scoped advisory lock without waiting. It is released
+// at the end of transaction, which db or ctx must be bound to. Supported only by Postgres drivers.
+func (db *DB) TryXactLock(ctx context.Context, key int64) (bool, error) {
+	if err := db.checkAdvisoryLocks(); err != nil {
+		return false, err
+	}
+
+	db = db.joinTx(ctx)
+	if db.tx == nil {
+		return false, errors.New("xact lock requires transaction")
+	}
+
+	var ok bool
+	err := db.tx.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock($1)", key).Scan(&ok)
+	return ok, errx.Wrap("try xact lock", err)
+}
+
+// NewLeaderElector returns leader elector, which holds session-scoped advisory lock with key
+// on the primary, while leading. Supported only by Postgres drivers.
+func (db *DB) NewLeaderElector(key int64, options ...dbx.LeaderOption) *dbx.LeaderElector {
+	return dbx.NewLeaderElector(func(ctx context.Context) (dbx.AdvisoryLock, bool, error) {
+		lock, ok, err := db.TryAcquireLock(ctx, key)
+		if !ok {
+			return nil, false, err
+		}
+
+		return lock, true, nil
+	}, options...)
+}
+
+func (db *DB) checkAdvisoryLocks() error {
+	if db.placeholderStyle != dbx.PlaceholderDollar {
+		return fmt.Errorf("advisory locks are not supported by %s driver", db.driverName)
+	}
+
+	return nil
+}
+
+// discardConn closes physical connection instead of returning it to pool.
+func discardConn(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+
+	newMockDB := func(t *testing.T) (*DB, sqlmock.Sqlmock) {
+		mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		assert.Nil(t, err)
+
+		db, err := NewDB("postgres", []string{"first"}, nopNodeChecker,
+			WithDBOpener(func(ctx context.Context, driverName, dsn string) (*sql.DB, error) {
+				return mockDB, nil
+			}))
+		assert.Nil(t, err)
+		t.Cleanup(db.Close)
+
+		return db, mock
+	}
+
+	t.Run("acquire and release", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectQuery("pg_advisory_lock").WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+		mock.ExpectPing()
+		mock.ExpectQuery("pg_advisory_unlock").WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_advisory_unlock"}).AddRow(true))
+
+		lock, err := db.AcquireLock(ctx, 42)
+		assert.Nil(t, err)
+
+		// Connection is pinned by lock until release.
+		node := db.Cluster.Nodes()[0]
+		assert.Equal(t, 1, node.DB().Stats().InUse)
+
+		assert.Nil(t, lock.Check(ctx))
+		assert.Nil(t, lock.Release(ctx))
+		assert.Equal(t, 0, node.DB().Stats().InUse)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("try acquire held", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectQuery("pg_try_advisory_lock").WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		lock, ok, err := db.TryAcquireLock(ctx, 42)
+		assert.Nil(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, lock)
+
+		node := db.Cluster.Nodes()[0]
+		assert.Equal(t, 0, node.DB().Stats().InUse)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("check broken conn", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		mock.ExpectQuery("pg_try_advisory_lock").WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectPing().WillReturnError(driver.ErrBadConn)
+
+		lock, ok, err := db.TryAcquireLock(ctx, 42)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+		assert.ErrorContains(t, lock.Check(ctx), "ping")
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsupported driver", func(t *testing.T) {
+		_, err := newSqlite3DB(t).AcquireLock(ctx, 42)
+		assert.ErrorContains(t, err, "advisory locks are not supported by sqlite3 driver")
+	})
+}
@@ -0,0 +1,173 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// Parameter limits of single statement.
+const (
+	SQLiteMaxParams       = 999 // Default for SQLite before 3.32.0, it is 32766 since then.
+	PostgresMaxParams     = 65535
+	MySQLMaxParams        = 65535
+	SQLServerMaxParams    = 2100
+	SQLServerMaxValueRows = 1000
+)
+
+type bulkInsertLimits struct {
+	maxParams int
+	maxRows   int // 0 means no limit
+}
+
+func getBulkInsertLimits(driverName string) bulkInsertLimits {
+	switch driverName {
+	case "postgres", "pgx":
+		return bulkInsertLimits{maxParams: PostgresMaxParams}
+	case "mysql":
+		return bulkInsertLimits{maxParams: MySQLMaxParams}
+	case "sqlserver", "mssql":
+		// SQL Server counts parameters strictly below the limit.
+		return bulkInsertLimits{maxParams: SQLServerMaxParams - 1, maxRows: SQLServerMaxValueRows}
+	default:
+		return bulkInsertLimits{maxParams: SQLiteMaxParams}
+	}
+}
+
+type bulkInsertOptions struct {
+	suffix    string
+	maxParams int
+	maxRows   int
+}
+
+type BulkInsertOption func(opts *bulkInsertOptions)
+
+// WithUpsert appends clause after VALUES of every statement, e.g.
+// "ON CONFLICT (id) DO UPDATE SET name = excluded.name" or "ON DUPLICATE KEY UPDATE name = VALUES(name)".
+func WithUpsert(clause string) BulkInsertOption {
+	return func(opts *bulkInsertOptions) {
+		opts.suffix = clause
+	}
+}
+
+// WithMaxParams overrides driver parameter limit of single statement, e.g. for SQLite 3.32.0+.
+func WithMaxParams(maxParams int) BulkInsertOption {
+	return func(opts *bulkInsertOptions) {
+		opts.maxParams = maxParams
+	}
+}
+
+// WithBatchRows limits number of rows in single statement.
+func WithBatchRows(maxRows int) BulkInsertOption {
+	return func(opts *bulkInsertOptions) {
+		opts.maxRows = maxRows
+	}
+}
+
+// BulkInsert inserts rows with multi-row INSERT ... VALUES statements, split into chunks within driver
+// parameter limits. Table and column names are not escaped. Statements are executed in the current
+// transaction, if ctx or db is bound to one, or in a new transaction otherwise,
+// so rows are inserted atomically. Returns number of affected rows.
+func (db *DB) BulkInsert(ctx context.Context, table string, columns []string,
+	rows [][]any, options ...BulkInsertOption) (int64, error) {
+	if len(columns) == 0 {
+		return 0, errors.New("no columns")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	opts := bulkInsertOptions{
+		maxParams: db.bulkInsertLimits.maxParams,
+		maxRows:   db.bulkInsertLimits.maxRows,
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	chunkRows := opts.maxParams / len(columns)
+	if opts.maxRows > 0 && opts.maxRows < chunkRows {
+		chunkRows = opts.maxRows
+	}
+	if chunkRows == 0 {
+		return 0, fmt.Errorf("%d columns exceed limit of %d parameters", len(columns), opts.maxParams)
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(columns))
+		}
+	}
+
+	insert := func(ctx context.Context) (int64, error) {
+		var total int64
+		for start := 0; start < len(rows); start += chunkRows {
+			end := min(start+chunkRows, len(rows))
+
+			query, args := db.bulkInsertQuery(table, columns, rows[start:end], opts.suffix)
+			res, err := db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return total, errx.Wrap("exec bulk insert chunk", err)
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return total, errx.Wrap("get rows affected", err)
+			}
+			total += affected
+		}
+
+		return total, nil
+	}
+
+	if db.joinTx(ctx).tx != nil || len(rows) <= chunkRows {
+		return insert(ctx)
+	}
+
+	var total int64
+	err := db.DoInTx(ctx, func(ctx context.Context) error {
+		var err error
+		total, err = insert(ctx)
+		return err
+	}, nil)
+
+	return total, err
+}
+
+func (db *DB) bulkInsertQuery(table string, columns []string, rows [][]any, suffix string) (string, []any) {
+	var b strings.Builder
+	args := make([]any, 0, len(rows)*len(columns))
+
+	b.WriteString("INSERT INTO ")
+	b.WriteString(table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(columns, ", "))
+	b.WriteString(") VALUES ")
+
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+
+			args = append(args, v)
+			b.WriteString(db.placeholderStyle.Placeholder(len(args)))
+		}
+		b.WriteByte(')')
+	}
+
+	if suffix != "" {
+		b.WriteByte(' ')
+		b.WriteString(suffix)
+	}
+
+	return b.String(), args
+}
@@ -0,0 +1,303 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// OpenDB returns single cluster-aware *sql.DB backed by db. It can be passed to libraries, which accept
+// only *sql.DB (ORMs, migration tools, query builders).
+func OpenDB(db *DB) *sql.DB {
+	return sql.OpenDB(NewConnector(db))
+}
+
+type connector struct {
+	db *DB
+}
+
+var _ driver.Connector = &connector{}
+
+// NewConnector returns driver.Connector backed by db. Connections of this connector do not hold
+// physical connections: every statement is routed to the cluster node using db strategies,
+// and transactions are pinned to the node they were started on.
+func NewConnector(db *DB) driver.Connector {
+	return &connector{db: db}
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if _, err := c.db.GetNode(ctx, dbx.WaitForAlive()); err != nil {
+		return nil, errx.Wrap("wait for alive node", err)
+	}
+
+	return &conn{db: c.db}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return &connectorDriver{connector: c}
+}
+
+type connectorDriver struct {
+	connector *connector
+}
+
+func (d *connectorDriver) Open(string) (driver.Conn, error) {
+	return d.connector.Connect(context.Background())
+}
+
+type conn struct {
+	db *DB
+	tx *DB
+}
+
+var (
+	_ driver.Conn               = &conn{}
+	_ driver.ConnBeginTx        = &conn{}
+	_ driver.ConnPrepareContext = &conn{}
+	_ driver.ExecerContext      = &conn{}
+	_ driver.QueryerContext     = &conn{}
+	_ driver.NamedValueChecker  = &conn{}
+	_ driver.Pinger             = &conn{}
+	_ driver.SessionResetter    = &conn{}
+	_ driver.Validator          = &conn{}
+)
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext does not prepare statement on server, because statement may be routed
+// to different nodes on every execution.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	if c.tx != nil {
+		err := c.tx.rollback(context.Background())
+		c.tx = nil
+		return errx.Wrap("rollback", err)
+	}
+
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("transaction is already started")
+	}
+
+	txDB, err := c.db.withTx(ctx, &sql.TxOptions{
+		Isolation: sql.IsolationLevel(opts.Isolation),
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return nil, errx.Wrap("with tx", err)
+	}
+
+	c.tx = txDB
+	return &tx{conn: c}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.target().ExecContext(ctx, query, namedValuesToArgs(args)...)
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.target().QueryContext(ctx, query, namedValuesToArgs(args)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConnRows(res)
+}
+
+// CheckNamedValue passes all values as is, so they are converted by the driver of the node.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	node, err := c.db.GetNode(ctx, dbx.WaitForAlive())
+	if err != nil {
+		return errx.Wrap("wait for alive node", err)
+	}
+
+	return node.DB().PingContext(ctx)
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.tx != nil {
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+// IsValid reports whether cluster has any alive nodes.
+func (c *conn) IsValid() bool {
+	return len(c.db.Cluster.AliveNodes().Alive) > 0
+}
+
+func (c *conn) target() *DB {
+	if c.tx != nil {
+		return c.tx
+	}
+
+	return c.db
+}
+
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	txDB := t.conn.tx
+	t.conn.tx = nil
+
+	if err := txDB.commit(context.Background()); err != nil {
+		txDB.TxHooks.RolledBack(context.Background(), err)
+		return err
+	}
+
+	txDB.TxHooks.Committed(context.Background())
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	txDB := t.conn.tx
+	t.conn.tx = nil
+
+	err := txDB.rollback(context.Background())
+	txDB.TxHooks.RolledBack(context.Background(), nil)
+	return err
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var (
+	_ driver.Stmt             = &stmt{}
+	_ driver.StmtExecContext  = &stmt{}
+	_ driver.StmtQueryContext = &stmt{}
+)
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+type connRows struct {
+	rows    *sql.Rows
+	columns []string
+	values  []any
+	dest    []any
+	onClose func()
+}
+
+func newConnRows(rows *sql.Rows) (*connRows, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, errx.Wrap("get columns", err)
+	}
+
+	r := &connRows{
+		rows:    rows,
+		columns: columns,
+		values:  make([]any, len(columns)),
+		dest:    make([]any, len(columns)),
+	}
+	for i := range r.values {
+		r.dest[i] = &r.values[i]
+	}
+
+	return r, nil
+}
+
+func (r *connRows) Columns() []string {
+	return r.columns
+}
+
+func (r *connRows) Close() error {
+	err := r.rows.Close()
+	if r.onClose != nil {
+		r.onClose()
+		r.onClose = nil
+	}
+
+	return err
+}
+
+func (r *connRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+
+		return io.EOF
+	}
+
+	if err := r.rows.Scan(r.dest...); err != nil {
+		return err
+	}
+
+	for i, v := range r.values {
+		dest[i] = v
+	}
+
+	return nil
+}
+
+func namedValuesToArgs(values []driver.NamedValue) []any {
+	args := make([]any, 0, len(values))
+	for _, v := range values {
+		if v.Name != "" {
+			args = append(args, sql.Named(v.Name, v.Value))
+			continue
+		}
+
+		args = append(args, v.Value)
+	}
+
+	return args
+}
+
+func valuesToNamedValues(values []driver.Value) []driver.NamedValue {
+	res := make([]driver.NamedValue, 0, len(values))
+	for i, v := range values {
+		res = append(res, driver.NamedValue{Ordinal: i + 1, Value: v})
+	}
+
+	return res
+}
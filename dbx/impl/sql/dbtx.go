@@ -0,0 +1,77 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+)
+
+// DBTX is an interface, which sqlc-generated code for database/sql expects.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type dbtx struct {
+	db *DB
+}
+
+var _ DBTX = &dbtx{}
+
+// DBTX returns sqlc-compatible adapter of db. Queries are routed to cluster nodes
+// and join transaction from context, as *DB methods do.
+func (db *DB) DBTX() DBTX {
+	return &dbtx{db: db}
+}
+
+func (d *dbtx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+func (d *dbtx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, query)
+}
+
+func (d *dbtx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, query, args...)
+}
+
+func (d *dbtx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	switch row := d.db.QueryRowContext(ctx, query, args...).(type) {
+	case *sql.Row:
+		return row
+	default:
+		return newErrSQLRow(row.Err())
+	}
+}
+
+// newErrSQLRow returns *sql.Row, which holds err. *sql.Row can not be constructed outside
+// of database/sql, so it is obtained from *sql.DB, which always fails to connect.
+func newErrSQLRow(err error) *sql.Row {
+	db := sql.OpenDB(&errConnector{err: err})
+	defer db.Close()
+
+	return db.QueryRowContext(context.Background(), "")
+}
+
+type errConnector struct {
+	err error
+}
+
+func (c *errConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c *errConnector) Driver() driver.Driver {
+	return &errDriver{err: c.err}
+}
+
+type errDriver struct {
+	err error
+}
+
+func (d *errDriver) Open(string) (driver.Conn, error) {
+	return nil, d.err
+}
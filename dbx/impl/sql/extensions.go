@@ -2,6 +2,7 @@ package sql
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/ValerySidorin/corex/dbx"
 	"github.com/ValerySidorin/corex/errx"
@@ -32,3 +33,134 @@ func QueryContext[T any](ctx context.Context, db *DB, query string,
 	res, err := dbx.Scan(rows, pointers)
 	return res, errx.Wrap("scan", err)
 }
+
+// QueryForEach is a generic query helper, which streams rows to f one by one.
+// Iteration stops on the first error, returned by f.
+func QueryForEach[T any](db *DB, query string,
+	pointers func(*T) []interface{}, f func(T) error, args ...any) error {
+	return QueryForEachContext(db.Ctx, db, query, pointers, f, args...)
+}
+
+// QueryForEachContext is a generic query helper with context, which streams rows to f one by one.
+// Iteration stops on the first error, returned by f, or when ctx is done.
+func QueryForEachContext[T any](ctx context.Context, db *DB, query string,
+	pointers func(*T) []interface{}, f func(T) error, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return errx.Wrap("generic query for each context", err)
+	}
+
+	return errx.Wrap("for each", dbx.ForEachContext(ctx, rows, pointers, f))
+}
+
+// QueryStructs is a generic query helper, which scans rows into structs by `db` tags.
+func QueryStructs[T any](db *DB, query string, args ...any) ([]T, error) {
+	return QueryStructsContext[T](db.Ctx, db, query, args...)
+}
+
+// QueryStructsContext is a generic query helper with context, which scans rows into structs by `db` tags.
+func QueryStructsContext[T any](ctx context.Context, db *DB, query string, args ...any) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap("generic query structs context", err)
+	}
+	defer rows.Close()
+
+	res, err := dbx.ScanStructs[T](rows, db.StructScanMode)
+	return res, errx.Wrap("scan structs", err)
+}
+
+// QueryStruct is a generic query helper, which scans the first row into struct by `db` tags.
+// Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func QueryStruct[T any](db *DB, query string, args ...any) (T, error) {
+	return QueryStructContext[T](db.Ctx, db, query, args...)
+}
+
+// QueryStructContext is a generic query helper with context, which scans the first row into struct
+// by `db` tags. Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func QueryStructContext[T any](ctx context.Context, db *DB, query string, args ...any) (T, error) {
+	return queryOneContext(ctx, db, query, func(columns []string) (func(*T) []interface{}, error) {
+		return dbx.StructPointers[T](columns, db.StructScanMode)
+	}, args...)
+}
+
+// QueryOne is a generic query helper, which scans the first row.
+// Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func QueryOne[T any](db *DB, query string,
+	pointers func(*T) []interface{}, args ...any) (T, error) {
+	return QueryOneContext(db.Ctx, db, query, pointers, args...)
+}
+
+// QueryOneContext is a generic query helper with context, which scans the first row.
+// Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func QueryOneContext[T any](ctx context.Context, db *DB, query string,
+	pointers func(*T) []interface{}, args ...any) (T, error) {
+	return queryOneContext(ctx, db, query, func([]string) (func(*T) []interface{}, error) {
+		return pointers, nil
+	}, args...)
+}
+
+// Get is a generic query helper, which scans the first row into T. Scalars are scanned
+// from single column, structs are scanned by `db` tags.
+// Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func Get[T any](db *DB, query string, args ...any) (T, error) {
+	return GetContext[T](db.Ctx, db, query, args...)
+}
+
+// GetContext is a generic query helper with context, which scans the first row into T. Scalars are
+// scanned from single column, structs are scanned by `db` tags.
+// Error, wrapping both dbx.ErrNotFound and sql.ErrNoRows, is returned, if there are no rows.
+func GetContext[T any](ctx context.Context, db *DB, query string, args ...any) (T, error) {
+	return queryOneContext(ctx, db, query, func(columns []string) (func(*T) []interface{}, error) {
+		return dbx.ValuePointers[T](columns, db.StructScanMode)
+	}, args...)
+}
+
+func queryOneContext[T any](ctx context.Context, db *DB, query string,
+	pointersBuilder func(columns []string) (func(*T) []interface{}, error), args ...any) (T, error) {
+	var res T
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return res, errx.Wrap("generic query one context", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return res, errx.Wrap("get columns", err)
+	}
+
+	pointers, err := pointersBuilder(columns)
+	if err != nil {
+		return res, errx.Wrap("get pointers", err)
+	}
+
+	res, ok, err := dbx.ScanOne(rows, pointers)
+	if err != nil {
+		return res, errx.Wrap("scan one", err)
+	}
+	if !ok {
+		return res, dbx.NotFound(sql.ErrNoRows)
+	}
+
+	return res, nil
+}
+
+// NewPaginator returns keyset paginator over query. Pages are fetched with QueryContext,
+// so they are read from ReadFromNodeStrategy node, unless db is bound to transaction.
+// See dbx.Paginator for query and keys requirements.
+func NewPaginator[T any](db *DB, query string, keys []dbx.KeysetColumn, pageSize int,
+	pointers func(*T) []interface{}, key func(*T) []any, args ...any) *dbx.Paginator[T] {
+	return &dbx.Paginator[T]{
+		Query:    query,
+		Args:     args,
+		Keys:     keys,
+		PageSize: pageSize,
+		Key:      key,
+		Fetch: func(ctx context.Context, query string, args ...any) ([]T, error) {
+			return QueryContext(ctx, db, query, pointers, args...)
+		},
+		Style: db.placeholderStyle,
+	}
+}
@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	registerHedgeDriver sync.Once
+	slowConnect         atomic.Bool
+)
+
+func TestHedgedQueryContext(t *testing.T) {
+	// The first connection after slowConnect is set is slow, so read is hedged to another node.
+	registerHedgeDriver.Do(func() {
+		sql.Register("sqlite3_hedge", &sqlite3.SQLiteDriver{
+			ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+				if slowConnect.CompareAndSwap(true, false) {
+					time.Sleep(200 * time.Millisecond)
+				}
+				return nil
+			},
+		})
+	})
+
+	dir := t.TempDir()
+	dsns := []string{filepath.Join(dir, "first.db"), filepath.Join(dir, "second.db")}
+	for _, dsn := range dsns {
+		db, err := sql.Open("sqlite3", dsn)
+		assert.Nil(t, err)
+		_, err = db.Exec("CREATE TABLE node (name TEXT); INSERT INTO node VALUES (?)", filepath.Base(dsn))
+		assert.Nil(t, err)
+		assert.Nil(t, db.Close())
+	}
+
+	db, err := NewDB("sqlite3_hedge", dsns, func(ctx context.Context, db *sql.DB) (bool, error) {
+		return false, nil
+	})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	_, err = db.Cluster.WaitForStandby(context.Background())
+	assert.Nil(t, err)
+	for _, node := range db.Cluster.Nodes() {
+		node.DB().SetMaxIdleConns(0)
+	}
+
+	db = db.WithHedgedReads(dbx.HedgePolicy{Delay: 10 * time.Millisecond})
+	slowConnect.Store(true)
+
+	started := time.Now()
+	rows, err := db.QueryContext(context.Background(), "SELECT name FROM node")
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(started), 200*time.Millisecond)
+
+	// Rows of winning read stay valid after QueryContext returns.
+	var names []string
+	for rows.Next() {
+		var name string
+		assert.Nil(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Nil(t, rows.Err())
+	assert.Nil(t, rows.Close())
+	assert.Len(t, names, 1)
+
+	hedged, wins := db.Hedger.Stats()
+	assert.Equal(t, int64(1), hedged)
+	assert.Equal(t, int64(1), wins)
+}
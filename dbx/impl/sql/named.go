@@ -0,0 +1,60 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// BindNamed rewrites `:name` and `@name` placeholders in query to positional ones of the driver.
+// Arguments are taken from map or tagged struct.
+func (db *DB) BindNamed(query string, arg any) (string, []any, error) {
+	return dbx.BindNamed(query, arg, db.placeholderStyle)
+}
+
+// NamedExec executes query with named arguments.
+func (db *DB) NamedExec(query string, arg any) (sql.Result, error) {
+	return db.NamedExecContext(db.Ctx, query, arg)
+}
+
+// NamedExecContext executes query with context and named arguments.
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	query, args, err := db.BindNamed(query, arg)
+	if err != nil {
+		return &nopResult{}, errx.Wrap("bind named", err)
+	}
+
+	return db.ExecContext(ctx, query, args...)
+}
+
+// NamedQuery queries underlying cluster with named arguments.
+func (db *DB) NamedQuery(query string, arg any) (*sql.Rows, error) {
+	return db.NamedQueryContext(db.Ctx, query, arg)
+}
+
+// NamedQueryContext queries underlying cluster with context and named arguments.
+func (db *DB) NamedQueryContext(ctx context.Context, query string, arg any) (*sql.Rows, error) {
+	query, args, err := db.BindNamed(query, arg)
+	if err != nil {
+		return nil, errx.Wrap("bind named", err)
+	}
+
+	return db.QueryContext(ctx, query, args...)
+}
+
+// NamedQueryRow queries row from underlying cluster with named arguments.
+func (db *DB) NamedQueryRow(query string, arg any) dbx.Row {
+	return db.NamedQueryRowContext(db.Ctx, query, arg)
+}
+
+// NamedQueryRowContext queries row from underlying cluster with context and named arguments.
+func (db *DB) NamedQueryRowContext(ctx context.Context, query string, arg any) dbx.Row {
+	query, args, err := db.BindNamed(query, arg)
+	if err != nil {
+		return newErrRow(errx.Wrap("bind named", err))
+	}
+
+	return db.QueryRowContext(ctx, query, args...)
+}
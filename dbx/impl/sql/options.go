@@ -1,11 +1,15 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/ValerySidorin/corex/dbx"
 )
 
+// StartupHook is called at the end of NewDB, e.g. to migrate database before serving.
+type StartupHook func(ctx context.Context, db *DB) error
+
 type Option func(db *DB)
 
 func WithGenericOptions(options ...dbx.Option[*sql.DB]) Option {
@@ -19,3 +23,10 @@ func WithDBOpener(dbOpener DBOpener) Option {
 		db.dbOpener = dbOpener
 	}
 }
+
+// WithStartupHook adds hook, which is called at the end of NewDB. NewDB closes cluster and fails, if hook fails.
+func WithStartupHook(hook StartupHook) Option {
+	return func(db *DB) {
+		db.startupHooks = append(db.startupHooks, hook)
+	}
+}
@@ -0,0 +1,54 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+type querier struct {
+	db *DB
+}
+
+var _ dbx.Querier = &querier{}
+
+// Querier returns driver-agnostic query API of db.
+func (db *DB) Querier() dbx.Querier {
+	return &querier{db: db}
+}
+
+func (q *querier) ExecContext(ctx context.Context, query string, args ...any) (dbx.Result, error) {
+	res, err := q.db.ExecContext(ctx, query, args...)
+	return res, errx.Wrap("querier exec", err)
+}
+
+func (q *querier) QueryContext(ctx context.Context, query string, args ...any) (dbx.QueryRows, error) {
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errx.Wrap("querier query", err)
+	}
+
+	return rows, nil
+}
+
+func (q *querier) QueryRowContext(ctx context.Context, query string, args ...any) dbx.Row {
+	return &queryRow{Row: q.db.QueryRowContext(ctx, query, args...)}
+}
+
+// queryRow is a row, which Scan returns error, wrapping both dbx.ErrNotFound and sql.ErrNoRows,
+// if there are no rows.
+type queryRow struct {
+	dbx.Row
+}
+
+func (r *queryRow) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dbx.NotFound(err)
+	}
+
+	return err
+}
@@ -0,0 +1,16 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/cluster"
+)
+
+// RegistryOpener returns dbx.Registry opener, which opens tenant *DB of driver with options.
+func RegistryOpener(driverName string, checker cluster.NodeChecker[*sql.DB], options ...Option) dbx.RegistryOpener[*DB] {
+	return func(ctx context.Context, tenantID string, dsns []string) (*DB, error) {
+		return NewDB(driverName, dsns, checker, options...)
+	}
+}
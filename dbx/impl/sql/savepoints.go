@@ -0,0 +1,47 @@
+package sql
+
+import "fmt"
+
+type savepointDialect struct {
+	savepointFormat  string
+	rollbackToFormat string
+	releaseFormat    string // Empty, if dialect does not support releasing savepoints
+}
+
+func (d savepointDialect) savepoint(name string) string {
+	return fmt.Sprintf(d.savepointFormat, name)
+}
+
+func (d savepointDialect) rollbackTo(name string) string {
+	return fmt.Sprintf(d.rollbackToFormat, name)
+}
+
+func (d savepointDialect) release(name string) string {
+	if d.releaseFormat == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(d.releaseFormat, name)
+}
+
+var (
+	sqlSavepointDialect = savepointDialect{
+		savepointFormat:  "SAVEPOINT %s",
+		rollbackToFormat: "ROLLBACK TO SAVEPOINT %s",
+		releaseFormat:    "RELEASE SAVEPOINT %s",
+	}
+
+	tsqlSavepointDialect = savepointDialect{
+		savepointFormat:  "SAVE TRANSACTION %s",
+		rollbackToFormat: "ROLLBACK TRANSACTION %s",
+	}
+)
+
+func getSavepointDialect(driverName string) savepointDialect {
+	switch driverName {
+	case "sqlserver", "mssql":
+		return tsqlSavepointDialect
+	default:
+		return sqlSavepointDialect
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ValerySidorin/corex/dbx"
@@ -18,11 +19,20 @@ type queryWithLockChecker func(query string) bool
 
 type DB struct {
 	*dbx.DB[*sql.DB]
-	genericOpts []dbx.Option[*sql.DB]
+	genericOpts  []dbx.Option[*sql.DB]
+	startupHooks []StartupHook
 
+	driverName           string
 	dbOpener             DBOpener
 	queryWithLockChecker queryWithLockChecker
-	tx                   *sql.Tx
+	savepointDialect     savepointDialect
+	placeholderStyle     dbx.PlaceholderStyle
+	bulkInsertLimits     bulkInsertLimits
+
+	tx        *sql.Tx
+	txDepth   int
+	savepoint string
+	conn      *sql.Conn
 }
 
 // NewDB returns an instance of *DB.
@@ -35,7 +45,11 @@ func NewDB(driverName string, dsns []string,
 		opt(resDB)
 	}
 
+	resDB.driverName = driverName
 	resDB.queryWithLockChecker = getQueryWithLockChecker(driverName)
+	resDB.savepointDialect = getSavepointDialect(driverName)
+	resDB.placeholderStyle = dbx.DialectFor(driverName).PlaceholderStyle()
+	resDB.bulkInsertLimits = getBulkInsertLimits(driverName)
 
 	var err error
 	resDB.DB, err = dbx.NewDB(driverName, dsns,
@@ -47,7 +61,23 @@ func NewDB(driverName string, dsns []string,
 		resDB.genericOpts...,
 	)
 
-	return resDB, errx.Wrap("init generic db", err)
+	if err != nil {
+		return resDB, errx.Wrap("init generic db", err)
+	}
+
+	if err := resDB.checkTenantScope(resDB.TenantScope); err != nil {
+		resDB.Close()
+		return resDB, err
+	}
+
+	for _, hook := range resDB.startupHooks {
+		if err := hook(resDB.Ctx, resDB); err != nil {
+			resDB.Close()
+			return resDB, errx.Wrap("run startup hook", err)
+		}
+	}
+
+	return resDB, nil
 }
 
 func (db *DB) WithCtx(ctx context.Context) *DB {
@@ -80,29 +110,42 @@ func (db *DB) WithDefaultNodeStrategy(strategy dbx.GetNodeStragegy) *DB {
 	return resDB
 }
 
-// DoTx executes passed function in transaction.
-func (db *DB) DoTx(f func(db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error, opts *sql.TxOptions) error {
-	newDB, err := db.withTx(context.Background(), opts)
-	if err != nil {
-		return errx.Wrap("with tx", err)
-	}
-	defer func() {
-		_ = newDB.tx.Rollback()
-	}()
+func (db *DB) WithReadRetryPolicy(policy dbx.RetryPolicy) *DB {
+	resDB := db.copy()
+	resDB.ReadRetryPolicy = policy
+	return resDB
+}
 
-	err = f(newDB)
-	if err != nil {
-		return errx.Wrap("exec func in tx", err)
-	}
+func (db *DB) WithTxRetryPolicy(policy dbx.TxRetryPolicy) *DB {
+	resDB := db.copy()
+	resDB.TxRetryPolicy = policy
+	return resDB
+}
 
-	if err := newDB.tx.Commit(); err != nil {
-		return errx.Wrap("commit", err)
-	}
+func (db *DB) WithStructScanMode(mode dbx.StructScanMode) *DB {
+	resDB := db.copy()
+	resDB.StructScanMode = mode
+	return resDB
+}
 
-	return nil
+// WithHedgedReads returns copy of db, which hedges reads with policy, e.g. for latency-sensitive endpoints.
+func (db *DB) WithHedgedReads(policy dbx.HedgePolicy) *DB {
+	resDB := db.copy()
+	resDB.Hedger = dbx.NewHedger(policy)
+	return resDB
+}
+
+// DoTx executes passed function in transaction.
+// Nested transactions are emulated with savepoints.
+func (db *DB) DoTx(f func(db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error, opts *sql.TxOptions) error {
+	return db.DoTxContext(context.Background(),
+		func(ctx context.Context, db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			return f(db)
+		}, opts)
 }
 
 // DoTxContext executes passed function in transaction.
+// Nested transactions are emulated with savepoints.
 func (db *DB) DoTxContext(
 	ctx context.Context,
 	f func(ctx context.Context, db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error,
@@ -111,22 +154,68 @@ func (db *DB) DoTxContext(
 	if err != nil {
 		return errx.Wrap("with tx", err)
 	}
+
+	var committed bool
 	defer func() {
-		_ = newDB.tx.Rollback()
+		if !committed {
+			_ = newDB.rollback(ctx)
+			newDB.TxHooks.RolledBack(ctx, err)
+		}
 	}()
 
-	err = f(ctx, newDB)
+	err = f(newDB.Ctx, newDB)
 	if err != nil {
 		return errx.Wrap("exec func in tx", err)
 	}
 
-	if err := newDB.tx.Commit(); err != nil {
-		return errx.Wrap("commit", err)
+	committed = true
+	if err := newDB.commit(ctx); err != nil {
+		newDB.TxHooks.RolledBack(ctx, err)
+		return errx.Wrap("commit", &dbx.CommitError{Err: err})
 	}
 
+	newDB.TxHooks.Committed(ctx)
 	return nil
 }
 
+// DoInTx executes passed function in transaction. Transaction is propagated through context,
+// so all *DB methods, called with this context, join it.
+func (db *DB) DoInTx(ctx context.Context, f func(ctx context.Context) error, opts *sql.TxOptions) error {
+	return db.DoTxContext(ctx,
+		func(ctx context.Context, _ dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			return f(ctx)
+		}, opts)
+}
+
+// DoRetryTx executes passed function in transaction and retries the whole transaction on another
+// primary according to TxRetryPolicy.
+func (db *DB) DoRetryTx(f func(db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error, opts *sql.TxOptions) error {
+	return db.DoRetryTxContext(context.Background(),
+		func(ctx context.Context, db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			return f(db)
+		}, opts)
+}
+
+// DoRetryTxContext executes passed function in transaction and retries the whole transaction on another
+// primary according to TxRetryPolicy. Nested transactions are not retried.
+func (db *DB) DoRetryTxContext(
+	ctx context.Context,
+	f func(ctx context.Context, db dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error,
+	opts *sql.TxOptions) error {
+	if db.joinTx(ctx).tx != nil {
+		return db.DoTxContext(ctx, f, opts)
+	}
+
+	return db.RetryTx(ctx, func(ctx context.Context) error {
+		return db.DoTxContext(ctx, f, opts)
+	})
+}
+
+// Dialect returns SQL dialect of db driver.
+func (db *DB) Dialect() dbx.Dialect {
+	return dbx.DialectFor(db.driverName)
+}
+
 func (db *DB) Tx() (*sql.Tx, error) {
 	if db.tx == nil {
 		return nil, errors.New("no sql tx")
@@ -137,43 +226,81 @@ func (db *DB) Tx() (*sql.Tx, error) {
 
 // Exec executes query.
 func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	db = db.joinTx(db.Ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.Exec(query, args...)
 		return res, errx.Wrap("exec in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.ExecContext(db.Ctx, query, args...)
+		return res, errx.Wrap("exec on conn", err)
+	}
+
 	conn, err := db.GetWriteToConn(db.Ctx)
 	if err != nil {
 		return &nopResult{}, errx.Wrap("wait for write to conn", err)
 	}
 
-	res, err := conn.Exec(query, args...)
+	res, err := db.execContext(db.Ctx, conn, query, args...)
 	return res, errx.Wrap("exec", err)
 }
 
 // ExecContext executes query with context.
 func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.ExecContext(ctx, query, args...)
 		return res, errx.Wrap("exec context in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.ExecContext(ctx, query, args...)
+		return res, errx.Wrap("exec context on conn", err)
+	}
+
 	conn, err := db.GetWriteToConn(ctx)
 	if err != nil {
 		return &nopResult{}, errx.Wrap("wait for write to conn", err)
 	}
 
-	res, err := conn.ExecContext(ctx, query, args...)
+	res, err := db.execContext(ctx, conn, query, args...)
 	return res, errx.Wrap("exec context", err)
 }
 
+// ExecExpect executes query and returns *dbx.RowsAffectedError, if it affected
+// other than expected number of rows.
+func (db *DB) ExecExpect(expected int64, query string, args ...any) error {
+	return db.ExecExpectContext(db.Ctx, expected, query, args...)
+}
+
+// ExecExpectContext executes query with context and returns *dbx.RowsAffectedError, if it affected
+// other than expected number of rows.
+func (db *DB) ExecExpectContext(ctx context.Context, expected int64, query string, args ...any) error {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return dbx.ExpectRowsAffected(res, expected)
+}
+
 // Prepare prepares query.
 func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	db = db.joinTx(db.Ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.Prepare(query)
 		return res, errx.Wrap("prepare in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.PrepareContext(db.Ctx, query)
+		return res, errx.Wrap("prepare on conn", err)
+	}
+
 	conn, err := db.GetDefaultConn(db.Ctx)
 	if err != nil {
 		return nil, errx.Wrap("wait for default conn", err)
@@ -185,11 +312,18 @@ func (db *DB) Prepare(query string) (*sql.Stmt, error) {
 
 // PrepareContext prepares query with context.
 func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.PrepareContext(ctx, query)
 		return res, errx.Wrap("prepare context in tx", err)
 	}
 
+	if db.conn != nil {
+		res, err := db.conn.PrepareContext(ctx, query)
+		return res, errx.Wrap("prepare context on conn", err)
+	}
+
 	conn, err := db.GetDefaultConn(ctx)
 	if err != nil {
 		return nil, errx.Wrap("wait for default conn", err)
@@ -201,62 +335,100 @@ func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, erro
 
 // Query queries underlying cluster.
 func (db *DB) Query(query string, args ...any) (*sql.Rows, error) {
-	return db.QueryContext(context.Background(), query, args...)
+	return db.QueryContext(db.Ctx, query, args...)
 }
 
 // QueryContext queries underlying cluster with context.
+// Reads, that failed with connection-level error, are retried on another node according to ReadRetryPolicy.
+// If Hedger is set, slow reads are hedged to another node. Locking reads and queries with side effects
+// (e.g. DML with RETURNING) are sent to WriteToNodeStrategy node and are not retried.
 func (db *DB) QueryContext(ctx context.Context,
 	query string, args ...any) (*sql.Rows, error) {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
 		res, err := db.tx.QueryContext(ctx, query, args...)
 		return res, errx.Wrap("query context in tx", err)
 	}
 
-	var (
-		conn *sql.DB
-		err  error
-	)
+	if db.conn != nil {
+		res, err := db.conn.QueryContext(ctx, query, args...)
+		return res, errx.Wrap("query context on conn", err)
+	}
 
-	if db.queryWithLockChecker(query) {
-		conn, err = db.GetReadFromConn(ctx)
-	} else {
-		conn, err = db.GetReadFromConn(ctx)
+	if db.queryWithLockChecker(query) || !dbx.IsReadOnlyQuery(query) {
+		conn, err := db.GetWriteToConn(ctx)
+		if err != nil {
+			return nil, errx.Wrap("wait for conn", err)
+		}
+
+		res, err := db.queryContext(ctx, conn, query, args...)
+		return res, errx.Wrap("query context", err)
 	}
+
+	rows, _, err := dbx.HedgedRead(ctx, db.DB, query, func(ctx context.Context, conn *sql.DB) (*sql.Rows, error) {
+		res, err := db.queryContext(ctx, conn, query, args...)
+		return res, errx.Wrap("query context", err)
+	}, func(rows *sql.Rows) {
+		_ = rows.Close()
+	})
 	if err != nil {
-		return nil, errx.Wrap("wait for conn", err)
+		return nil, err
 	}
 
-	res, err := conn.QueryContext(ctx, query, args...)
-	return res, errx.Wrap("query context", err)
+	// Rows are closed, when context of winning read is cancelled, so it is left to be released with ctx
+	// and rows are returned as is.
+	return rows, nil
 }
 
 // QueryRow queries row from underlying cluster.
 func (db *DB) QueryRow(query string, args ...any) dbx.Row {
-	return db.QueryRowContext(context.Background(), query, args...)
+	return db.QueryRowContext(db.Ctx, query, args...)
 }
 
 // QueryRowContext queries row from underlying cluster with context.
+// Reads, that failed with connection-level error, are retried on another node according to ReadRetryPolicy.
+// Locking reads and queries with side effects are routed as in QueryContext.
 func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) dbx.Row {
+	db = db.joinTx(ctx)
+
 	if db.tx != nil {
-		return db.tx.QueryRowContext(ctx, query)
+		return db.tx.QueryRowContext(ctx, query, args...)
 	}
 
-	var (
-		conn *sql.DB
-		err  error
-	)
+	if db.conn != nil {
+		return db.conn.QueryRowContext(ctx, query, args...)
+	}
 
-	if db.queryWithLockChecker(query) {
-		conn, err = db.GetReadFromConn(ctx)
-	} else {
-		conn, err = db.GetReadFromConn(ctx)
+	if db.queryWithLockChecker(query) || !dbx.IsReadOnlyQuery(query) {
+		conn, err := db.GetWriteToConn(ctx)
+		if err != nil {
+			return newErrRow(errx.Wrap("wait for conn", err))
+		}
+
+		row, err := db.queryRowContext(ctx, conn, query, args...)
+		if err != nil {
+			return newErrRow(err)
+		}
+
+		return row
 	}
 
-	if err != nil {
-		return newErrRow(errx.Wrap("wait for conn", err))
+	var row dbx.Row
+	err := db.DoRead(ctx, query, func(conn *sql.DB) error {
+		var err error
+		row, err = db.queryRowContext(ctx, conn, query, args...)
+		if err != nil {
+			return err
+		}
+
+		return row.Err()
+	})
+	if row == nil {
+		return newErrRow(err)
 	}
 
-	return conn.QueryRowContext(ctx, query, args...)
+	return row
 }
 
 func newDB() *DB {
@@ -266,18 +438,43 @@ func newDB() *DB {
 }
 
 // withTx returns a copied version of *DB with new transaction.
+// If *DB is already in transaction, savepoint is created instead.
 func (db *DB) withTx(ctx context.Context, opts *sql.TxOptions) (*DB, error) {
-	if db.tx != nil {
-		return nil, errors.New("nested transactions are not supported by database/sql package")
-	}
-
 	var (
 		conn *sql.DB
 		err  error
 	)
 
-	newDB := db.copy()
-	newDB.Ctx = ctx
+	newDB := db.joinTx(ctx).copy()
+	newDB.Ctx = newDB.ContextWithTx(ctx, newDB)
+
+	if newDB.tx != nil {
+		newDB.txDepth++
+		newDB.savepoint = fmt.Sprintf("dbx_sp_%d", newDB.txDepth)
+		newDB.TxHooks = newDB.TxHooks.Nested()
+
+		if _, err := newDB.tx.ExecContext(ctx, newDB.savepointDialect.savepoint(newDB.savepoint)); err != nil {
+			return nil, errx.Wrap("create savepoint", err)
+		}
+
+		return newDB, nil
+	}
+
+	if newDB.conn != nil {
+		tx, err := newDB.conn.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, errx.Wrap("begin tx on conn", err)
+		}
+
+		if err := newDB.applyTenantScope(ctx, tx.ExecContext, true); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+
+		newDB.tx = tx
+		newDB.TxHooks = &dbx.TxHooks{}
+		return newDB, nil
+	}
 
 	if opts == nil || !opts.ReadOnly {
 		conn, err = newDB.GetWriteToConn(ctx)
@@ -294,17 +491,81 @@ func (db *DB) withTx(ctx context.Context, opts *sql.TxOptions) (*DB, error) {
 		return nil, errx.Wrap("begin tx", err)
 	}
 
+	if err := newDB.applyTenantScope(ctx, tx.ExecContext, true); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
 	newDB.tx = tx
+	newDB.TxHooks = &dbx.TxHooks{}
 	return newDB, nil
 }
 
+// commit commits transaction or releases savepoint in case of nested transaction.
+func (db *DB) commit(ctx context.Context) error {
+	if db.savepoint == "" {
+		return db.tx.Commit()
+	}
+
+	query := db.savepointDialect.release(db.savepoint)
+	if query == "" {
+		return nil
+	}
+
+	_, err := db.tx.ExecContext(ctx, query)
+	return errx.Wrap("release savepoint", err)
+}
+
+// rollback rolls back transaction or rolls back to savepoint in case of nested transaction.
+func (db *DB) rollback(ctx context.Context) error {
+	if db.savepoint == "" {
+		return db.tx.Rollback()
+	}
+
+	_, err := db.tx.ExecContext(ctx, db.savepointDialect.rollbackTo(db.savepoint))
+	return errx.Wrap("rollback to savepoint", err)
+}
+
+// joinTx returns *DB bound to transaction from ctx, if db itself is not bound to transaction.
+// Otherwise it returns *DB bound to pinned connection from ctx, if db itself is not bound to one.
+func (db *DB) joinTx(ctx context.Context) *DB {
+	if db.tx != nil {
+		return db
+	}
+
+	if tx, ok := db.TxFromContext(ctx); ok {
+		if txDB, ok := tx.(*DB); ok {
+			return txDB
+		}
+	}
+
+	if db.conn != nil {
+		return db
+	}
+
+	if conn, ok := db.ConnFromContext(ctx); ok {
+		if connDB, ok := conn.(*DB); ok {
+			return connDB
+		}
+	}
+
+	return db
+}
+
 func (db *DB) copy() *DB {
 	return &DB{
 		DB:                   db.DB.Copy(),
 		genericOpts:          db.genericOpts,
+		driverName:           db.driverName,
 		dbOpener:             db.dbOpener,
 		queryWithLockChecker: db.queryWithLockChecker,
+		savepointDialect:     db.savepointDialect,
+		placeholderStyle:     db.placeholderStyle,
+		bulkInsertLimits:     db.bulkInsertLimits,
 		tx:                   db.tx,
+		txDepth:              db.txDepth,
+		savepoint:            db.savepoint,
+		conn:                 db.conn,
 	}
 }
 
@@ -312,6 +573,10 @@ func _() dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions] {
 	return &DB{}
 }
 
+func _() dbx.TxManager[*sql.TxOptions] {
+	return &DB{}
+}
+
 func getQueryWithLockChecker(driverName string) queryWithLockChecker {
 	switch driverName {
 	case "postgres", "pgx":
@@ -3,10 +3,13 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"path/filepath"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ValerySidorin/corex/dbx"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,6 +32,561 @@ func TestWithCtx(t *testing.T) {
 	assert.Nil(t, newDB.Ctx.Err())
 }
 
+func TestStartupHookFailure(t *testing.T) {
+	var opened *sql.DB
+	hookErr := errors.New("hook failed")
+
+	_, err := NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")}, nopNodeChecker,
+		WithDBOpener(func(ctx context.Context, driverName, dsn string) (*sql.DB, error) {
+			db, err := sql.Open(driverName, dsn)
+			opened = db
+			return db, err
+		}),
+		WithStartupHook(func(ctx context.Context, db *DB) error {
+			return hookErr
+		}))
+	assert.ErrorIs(t, err, hookErr)
+	assert.ErrorContains(t, opened.Ping(), "database is closed")
+}
+
+func TestTenantScopeDialect(t *testing.T) {
+	scope := &dbx.TenantScope{Setting: "app.tenant_id"}
+
+	_, err := NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")}, nopNodeChecker,
+		WithGenericOptions(dbx.WithTenantScope[*sql.DB](scope)))
+	assert.ErrorContains(t, err, "tenant scope is not supported by sqlite dialect")
+
+	db, err := NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")}, nopNodeChecker)
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := dbx.ContextWithTenant(context.Background(), "42")
+	_, err = db.WithTenantScope(scope).ExecContext(ctx, "select 1")
+	assert.ErrorContains(t, err, "tenant scope is not supported by sqlite dialect")
+
+	err = db.WithTenantScope(scope).DoInTx(ctx, func(ctx context.Context) error { return nil }, nil)
+	assert.ErrorContains(t, err, "tenant scope is not supported by sqlite dialect")
+}
+
+func TestTenantScopeStatements(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+
+	db, err := NewDB("postgres", []string{"first"}, nopNodeChecker,
+		WithDBOpener(func(ctx context.Context, driverName, dsn string) (*sql.DB, error) {
+			return mockDB, nil
+		}))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	db = db.WithTenantScope(&dbx.TenantScope{Setting: "app.tenant_id"})
+	ctx := dbx.ContextWithTenant(context.Background(), "42")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT set_config\\(\\$1, \\$2, true\\)").WithArgs("app.tenant_id", "42").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select name from users").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo").AddRow("bar"))
+	mock.ExpectCommit()
+
+	rows, err := db.QueryContext(ctx, "select name from users")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		assert.Nil(t, rows.Scan(&name))
+		names = append(names, name)
+		assert.NotNil(t, mock.ExpectationsWereMet(), "transaction is committed after rows are closed")
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []string{"foo", "bar"}, names)
+	assert.Nil(t, rows.Close())
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("set_config").WithArgs("app.tenant_id", "42").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select count").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectCommit()
+
+	var count int
+	assert.Nil(t, db.QueryRowContext(ctx, "select count(*) from users").Scan(&count))
+	assert.Equal(t, 2, count)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestNestedTx(t *testing.T) {
+	db := newSqlite3DB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "create table foo (id integer not null primary key)")
+	assert.Nil(t, err)
+
+	errNested := errors.New("nested")
+	err = db.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+		txDB := tx.(*DB)
+		if _, err := txDB.ExecContext(ctx, "insert into foo(id) values(1)"); err != nil {
+			return err
+		}
+
+		err := txDB.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			if _, err := tx.(*DB).ExecContext(ctx, "insert into foo(id) values(2)"); err != nil {
+				return err
+			}
+			return errNested
+		}, nil)
+		assert.ErrorIs(t, err, errNested)
+
+		return txDB.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			_, err := tx.(*DB).ExecContext(ctx, "insert into foo(id) values(3)")
+			return err
+		}, nil)
+	}, nil)
+	assert.Nil(t, err)
+
+	ids, err := Query(db, "select id from foo order by id", func(id *int) []interface{} {
+		return []interface{}{id}
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 3}, ids)
+}
+
+func TestTxHooks(t *testing.T) {
+	db := newSqlite3DB(t)
+	ctx := context.Background()
+
+	t.Run("commit", func(t *testing.T) {
+		var events []string
+		err := db.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			tx.OnCommit(func(ctx context.Context) { events = append(events, "commit") })
+			tx.OnRollback(func(ctx context.Context, err error) { events = append(events, "rollback") })
+
+			return tx.(*DB).DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+				tx.OnCommit(func(ctx context.Context) { events = append(events, "nested commit") })
+				assert.Empty(t, events)
+				return nil
+			}, nil)
+		}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"commit", "nested commit"}, events)
+	})
+
+	t.Run("nested rollback", func(t *testing.T) {
+		errNested := errors.New("nested")
+		var events []string
+		err := db.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			tx.OnCommit(func(ctx context.Context) { events = append(events, "commit") })
+
+			err := tx.(*DB).DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+				tx.OnCommit(func(ctx context.Context) { events = append(events, "nested commit") })
+				tx.OnRollback(func(ctx context.Context, err error) { events = append(events, "nested rollback") })
+				return errNested
+			}, nil)
+			assert.ErrorIs(t, err, errNested)
+			assert.Equal(t, []string{"nested rollback"}, events)
+			return nil
+		}, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"nested rollback", "commit"}, events)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		errTx := errors.New("tx")
+		var rollbackErr error
+		err := db.DoTxContext(ctx, func(ctx context.Context, tx dbx.DBxer[*sql.DB, *sql.Tx, *sql.TxOptions]) error {
+			tx.OnCommit(func(ctx context.Context) { t.Error("commit hook called") })
+			tx.OnRollback(func(ctx context.Context, err error) { rollbackErr = err })
+			return errTx
+		}, nil)
+		assert.ErrorIs(t, err, errTx)
+		assert.ErrorIs(t, rollbackErr, errTx)
+	})
+}
+
+func TestDoInTx(t *testing.T) {
+	db := newSqlite3DB(t)
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "create table foo (id integer not null primary key)")
+	assert.Nil(t, err)
+
+	errTx := errors.New("tx")
+	err = db.DoInTx(ctx, func(ctx context.Context) error {
+		// Plain *DB joins transaction from context.
+		if _, err := db.ExecContext(ctx, "insert into foo(id) values(1)"); err != nil {
+			return err
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "select count(*) from foo").Scan(&count); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, count)
+
+		// Methods without context join transaction from bound context.
+		bound := db.WithCtx(ctx)
+		if err := bound.QueryRow("select count(*) from foo").Scan(&count); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, count)
+
+		return errTx
+	}, nil)
+	assert.ErrorIs(t, err, errTx)
+
+	var count int
+	assert.Nil(t, db.QueryRowContext(ctx, "select count(*) from foo").Scan(&count))
+	assert.Equal(t, 0, count)
+}
+
+func TestQuerier(t *testing.T) {
+	q := newSqlite3DB(t).Querier()
+	ctx := context.Background()
+
+	_, err := q.ExecContext(ctx, "create table foo (id integer not null primary key, name text)")
+	assert.Nil(t, err)
+
+	res, err := q.ExecContext(ctx, "insert into foo(id, name) values(1, 'foo'), (2, 'bar')")
+	assert.Nil(t, err)
+	affected, err := res.RowsAffected()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	rows, err := q.QueryContext(ctx, "select id, name from foo order by id")
+	assert.Nil(t, err)
+	cols, err := rows.Columns()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "name"}, cols)
+	assert.Nil(t, rows.Close())
+
+	var name string
+	assert.Nil(t, q.QueryRowContext(ctx, "select name from foo where id = ?", 2).Scan(&name))
+	assert.Equal(t, "bar", name)
+
+	err = q.QueryRowContext(ctx, "select name from foo where id = ?", 3).Scan(&name)
+	assert.ErrorIs(t, err, dbx.ErrNotFound)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestDBTX(t *testing.T) {
+	dbtx := newSqlite3DB(t).DBTX()
+
+	var res int
+	assert.Nil(t, dbtx.QueryRowContext(context.Background(), "select 1").Scan(&res))
+	assert.Equal(t, 1, res)
+
+	errRow := errors.New("row")
+	assert.ErrorIs(t, newErrSQLRow(errRow).Scan(&res), errRow)
+}
+
+func TestConnector(t *testing.T) {
+	stdDB := OpenDB(newSqlite3DB(t))
+	defer stdDB.Close()
+
+	_, err := stdDB.Exec("create table foo (id integer not null primary key, name text)")
+	assert.Nil(t, err)
+
+	tx, err := stdDB.Begin()
+	assert.Nil(t, err)
+	_, err = tx.Exec("insert into foo(id, name) values(?, ?)", 1, "foo")
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Rollback())
+
+	stmt, err := stdDB.Prepare("insert into foo(id, name) values(?, ?)")
+	assert.Nil(t, err)
+	_, err = stmt.Exec(2, "bar")
+	assert.Nil(t, err)
+	assert.Nil(t, stmt.Close())
+
+	rows, err := stdDB.Query("select id, name from foo")
+	assert.Nil(t, err)
+	defer rows.Close()
+
+	var (
+		ids   []int
+		names []string
+	)
+	for rows.Next() {
+		var (
+			id   int
+			name string
+		)
+		assert.Nil(t, rows.Scan(&id, &name))
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	assert.Nil(t, rows.Err())
+	assert.Equal(t, []int{2}, ids)
+	assert.Equal(t, []string{"bar"}, names)
+}
+
+func TestQueryStructs(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key, name text, note text)")
+	assert.Nil(t, err)
+	_, err = db.Exec("insert into foo(id, name, note) values(1, 'foo', null), (2, 'bar', 'baz')")
+	assert.Nil(t, err)
+
+	type foo struct {
+		ID   int
+		Name string
+		Note *string
+	}
+
+	res, err := QueryStructs[foo](db, "select id, name, note from foo order by id")
+	assert.Nil(t, err)
+	assert.Len(t, res, 2)
+	assert.Nil(t, res[0].Note)
+	assert.Equal(t, "baz", *res[1].Note)
+
+	one, err := QueryStruct[foo](db, "select * from foo where id = ?", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", one.Name)
+
+	_, err = QueryStruct[foo](db, "select * from foo where id = ?", 3)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = QueryStructs[foo](db, "select id, name as unknown from foo")
+	assert.NotNil(t, err)
+
+	_, err = QueryStructs[foo](db.WithStructScanMode(dbx.ScanLenient), "select id, name as unknown from foo")
+	assert.Nil(t, err)
+}
+
+func TestGet(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key, name text)")
+	assert.Nil(t, err)
+	_, err = db.Exec("insert into foo(id, name) values(1, 'foo'), (2, 'bar')")
+	assert.Nil(t, err)
+
+	cnt, err := Get[int](db, "select count(*) from foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cnt)
+
+	name, err := QueryOne(db, "select name from foo where id = ?", func(s *string) []interface{} {
+		return []interface{}{s}
+	}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "bar", name)
+
+	_, err = Get[string](db, "select name from foo where id = ?", 3)
+	assert.ErrorIs(t, err, dbx.ErrNotFound)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = Get[int](db, "select id, name from foo")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, db.ExecExpect(1, "update foo set name = 'baz' where id = ?", 1))
+
+	var rowsErr *dbx.RowsAffectedError
+	err = db.ExecExpect(1, "update foo set name = 'baz' where id = ?", 3)
+	assert.ErrorAs(t, err, &rowsErr)
+	assert.Equal(t, int64(0), rowsErr.Actual)
+}
+
+func TestNamed(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key, name text)")
+	assert.Nil(t, err)
+
+	type foo struct {
+		ID   int
+		Name string
+	}
+
+	_, err = db.NamedExec("insert into foo(id, name) values(:id, :name)", foo{ID: 1, Name: "foo"})
+	assert.Nil(t, err)
+
+	var name string
+	err = db.NamedQueryRow("select name from foo where id = :id", map[string]any{"id": 1}).Scan(&name)
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", name)
+
+	_, err = db.NamedExec("delete from foo where id = :id", map[string]any{})
+	assert.NotNil(t, err)
+}
+
+func TestQueryForEach(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key)")
+	assert.Nil(t, err)
+	_, err = db.Exec("insert into foo(id) values(1), (2), (3)")
+	assert.Nil(t, err)
+
+	pointers := func(id *int) []interface{} {
+		return []interface{}{id}
+	}
+
+	var ids []int
+	err = QueryForEach(db, "select id from foo order by id", pointers, func(id int) error {
+		ids = append(ids, id)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	stopErr := errors.New("stop")
+	ids = nil
+	err = QueryForEach(db, "select id from foo order by id", pointers, func(id int) error {
+		ids = append(ids, id)
+		return stopErr
+	})
+	assert.ErrorIs(t, err, stopErr)
+	assert.Equal(t, []int{1}, ids)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ids = nil
+	err = QueryForEachContext(ctx, db, "select id from foo order by id", pointers, func(id int) error {
+		ids = append(ids, id)
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []int{1}, ids)
+}
+
+func TestBulkInsert(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key, name text)")
+	assert.Nil(t, err)
+
+	rows := make([][]any, 0, 10)
+	for i := 1; i <= 10; i++ {
+		rows = append(rows, []any{i, "foo"})
+	}
+
+	n, err := db.BulkInsert(context.Background(), "foo", []string{"id", "name"}, rows, WithMaxParams(6))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), n)
+
+	n, err = db.BulkInsert(context.Background(), "foo", []string{"id", "name"},
+		[][]any{{1, "bar"}, {11, "bar"}}, WithUpsert("ON CONFLICT (id) DO UPDATE SET name = excluded.name"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	cnt, err := Get[int](db, "select count(*) from foo where name = 'bar'")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, cnt)
+
+	// Chunk with duplicate key fails, so the whole insert is rolled back.
+	_, err = db.BulkInsert(context.Background(), "foo", []string{"id", "name"},
+		[][]any{{12, "baz"}, {13, "baz"}, {1, "baz"}}, WithBatchRows(2))
+	assert.NotNil(t, err)
+
+	cnt, err = Get[int](db, "select count(*) from foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 11, cnt)
+}
+
+func TestPaginator(t *testing.T) {
+	db := newSqlite3DB(t)
+
+	_, err := db.Exec("create table foo (id integer not null primary key, score integer, deleted bool)")
+	assert.Nil(t, err)
+	_, err = db.Exec(`insert into foo(id, score, deleted) values
+		(1, 10, false), (2, 20, false), (3, 20, false), (4, 20, false), (5, 30, false), (6, 30, true)`)
+	assert.Nil(t, err)
+
+	type foo struct {
+		ID    int
+		Score sql.NullInt64
+	}
+
+	pointers := func(f *foo) []interface{} { return []interface{}{&f.ID, &f.Score} }
+	key := func(f *foo) []any { return []any{f.Score, f.ID} }
+	p := NewPaginator(db, "select id, score from foo where deleted = ?",
+		[]dbx.KeysetColumn{dbx.Desc("score"), dbx.Asc("id")}, 2, pointers, key, false)
+
+	var (
+		ids    []int
+		cursor string
+		pages  int
+	)
+	for {
+		page, err := p.Page(context.Background(), cursor)
+		assert.Nil(t, err)
+		pages++
+
+		for _, f := range page.Items {
+			ids = append(ids, f.ID)
+		}
+
+		if page.Next == "" {
+			break
+		}
+		cursor = page.Next
+	}
+
+	assert.Equal(t, []int{5, 2, 3, 4, 1}, ids)
+	assert.Equal(t, 3, pages)
+
+	_, err = p.Page(context.Background(), "garbage")
+	assert.ErrorIs(t, err, dbx.ErrInvalidCursor)
+
+	// Cursor of another keyset is rejected.
+	asc := NewPaginator(db, "select id, score from foo where deleted = ?",
+		[]dbx.KeysetColumn{dbx.Asc("score"), dbx.Asc("id")}, 1, pointers, key, false)
+	_, err = asc.Page(context.Background(), cursor)
+	assert.ErrorIs(t, err, dbx.ErrInvalidCursor)
+
+	// NULL key can not be sought after, so it is rejected.
+	_, err = db.Exec("update foo set score = null where id = 3")
+	assert.Nil(t, err)
+	_, err = asc.Page(context.Background(), "")
+	assert.ErrorContains(t, err, "keyset column score is NULL")
+}
+
+func newSqlite3DB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")}, nopNodeChecker)
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	return db
+}
+
 func nopNodeChecker(ctx context.Context, db *sql.DB) (bool, error) {
 	return true, nil
 }
+
+func TestWithConn(t *testing.T) {
+	db := newSqlite3DB(t)
+	ctx := context.Background()
+
+	err := db.WithConn(ctx, dbx.WaitForPrimary(), func(ctx context.Context, conn *DB) error {
+		// Temp table is visible only to pinned connection.
+		if _, err := conn.ExecContext(ctx, "create temp table foo (id integer not null primary key)"); err != nil {
+			return err
+		}
+
+		err := db.DoInTx(ctx, func(ctx context.Context) error {
+			_, err := db.ExecContext(ctx, "insert into foo(id) values(1)")
+			return err
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "select count(*) from foo").Scan(&count); err != nil {
+			return err
+		}
+		assert.Equal(t, 1, count)
+
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// Session state is not leaked to pool.
+	_, err = db.ExecContext(ctx, "insert into foo(id) values(2)")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,224 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// WithTenantScope returns copy of db with tenant scope. Tenant scope uses Postgres settings,
+// so with other dialects statements and transactions of returned db fail.
+func (db *DB) WithTenantScope(scope *dbx.TenantScope) *DB {
+	resDB := db.copy()
+	resDB.TenantScope = scope
+	return resDB
+}
+
+func (db *DB) checkTenantScope(scope *dbx.TenantScope) error {
+	if scope != nil && db.Dialect() != dbx.DialectPostgres {
+		return fmt.Errorf("tenant scope is not supported by %s dialect", db.Dialect())
+	}
+
+	return nil
+}
+
+// applyTenantScope applies tenant scope of ctx to session or, if local is true, to transaction.
+func (db *DB) applyTenantScope(ctx context.Context,
+	exec func(ctx context.Context, query string, args ...any) (sql.Result, error), local bool) error {
+	if db.TenantScope == nil {
+		return nil
+	}
+
+	if err := db.checkTenantScope(db.TenantScope); err != nil {
+		return err
+	}
+
+	query, args, err := db.TenantScope.Query(ctx, local)
+	if err != nil || query == "" {
+		return err
+	}
+
+	_, err = exec(ctx, query, args...)
+	return errx.Wrap("apply tenant scope", err)
+}
+
+// checkout gets connection from pool and applies tenant scope of ctx to it.
+func (db *DB) checkout(ctx context.Context, pool *sql.DB) (*sql.Conn, error) {
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return nil, errx.Wrap("get conn", err)
+	}
+
+	if err := db.applyTenantScope(ctx, conn.ExecContext, false); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// beginScoped begins transaction of single statement and applies tenant scope of ctx to it.
+func (db *DB) beginScoped(ctx context.Context, pool *sql.DB) (*sql.Tx, error) {
+	tx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errx.Wrap("begin tx", err)
+	}
+
+	if err := db.applyTenantScope(ctx, tx.ExecContext, true); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// The following methods execute statements on pool. If tenant scope is set, every statement is executed
+// in its own transaction, which applies scope with SET LOCAL semantics, so connection needs no reset.
+// Transaction of query is committed, when its rows are closed or its row is scanned.
+
+func (db *DB) execContext(ctx context.Context, pool *sql.DB, query string, args ...any) (sql.Result, error) {
+	if db.TenantScope == nil {
+		return pool.ExecContext(ctx, query, args...)
+	}
+
+	tx, err := db.beginScoped(ctx, pool)
+	if err != nil {
+		return &nopResult{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return res, err
+	}
+
+	return res, errx.Wrap("commit", tx.Commit())
+}
+
+// queryContext returns rows of tenant query through rowsOnClose, so their columns are reported
+// with driver value types.
+func (db *DB) queryContext(ctx context.Context, pool *sql.DB, query string, args ...any) (*sql.Rows, error) {
+	if db.TenantScope == nil {
+		return pool.QueryContext(ctx, query, args...)
+	}
+
+	tx, err := db.beginScoped(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return rowsOnClose(ctx, rows, func() { _ = tx.Commit() })
+}
+
+func (db *DB) queryRowContext(ctx context.Context, pool *sql.DB, query string, args ...any) (dbx.Row, error) {
+	if db.TenantScope == nil {
+		return pool.QueryRowContext(ctx, query, args...), nil
+	}
+
+	tx, err := db.beginScoped(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txRow{row: tx.QueryRowContext(ctx, query, args...), tx: tx}, nil
+}
+
+// txRow is a row of statement, executed in its own transaction. Transaction is committed, when row
+// is scanned, and is rolled back, if statement failed.
+type txRow struct {
+	row *sql.Row
+	tx  *sql.Tx
+}
+
+func (r *txRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	commitErr := r.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return errx.Wrap("commit", commitErr)
+}
+
+func (r *txRow) Err() error {
+	err := r.row.Err()
+	if err != nil {
+		_ = r.tx.Rollback()
+	}
+
+	return err
+}
+
+type closeHookKey struct{}
+
+// closeHookDB serves rows, passed in context, so they are exposed as *sql.Rows with close hook.
+var closeHookDB = sql.OpenDB(closeHookConnector{})
+
+// rowsOnClose returns rows, which call onClose after they are closed. *sql.Rows can not be wrapped,
+// so rows are exposed through closeHookDB.
+func rowsOnClose(ctx context.Context, rows *sql.Rows, onClose func()) (*sql.Rows, error) {
+	r, err := newConnRows(rows)
+	if err != nil {
+		onClose()
+		return nil, err
+	}
+	r.onClose = onClose
+
+	res, err := closeHookDB.QueryContext(context.WithValue(ctx, closeHookKey{}, r), "")
+	if err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+
+	return res, nil
+}
+
+type closeHookConnector struct{}
+
+func (c closeHookConnector) Connect(context.Context) (driver.Conn, error) {
+	return closeHookConn{}, nil
+}
+
+func (c closeHookConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c closeHookConnector) Open(string) (driver.Conn, error) {
+	return closeHookConn{}, nil
+}
+
+type closeHookConn struct{}
+
+var _ driver.QueryerContext = closeHookConn{}
+
+func (c closeHookConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	rows, ok := ctx.Value(closeHookKey{}).(*connRows)
+	if !ok {
+		return nil, errors.New("no rows in context")
+	}
+
+	return rows, nil
+}
+
+func (c closeHookConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (c closeHookConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c closeHookConn) Close() error {
+	return nil
+}
@@ -0,0 +1,239 @@
+// Package jobqueue implements database-backed job queue. Jobs are enqueued in the current transaction,
+// if ctx is bound to one, and fetched by workers on the primary with FOR UPDATE SKIP LOCKED.
+// Postgres and SQLite dialects are supported. SQLite has no SKIP LOCKED, but serializes writers,
+// so it is suitable for tests and single-process deployments.
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/impl/pgxpoolv5"
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const (
+	DefaultTable       = "dbx_jobs"
+	DefaultQueue       = "default"
+	DefaultMaxAttempts = 25
+)
+
+// Job statuses.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDead    = "dead"
+)
+
+// sqliteNow is current time in SQLite with milliseconds. Times are stored as text in UTC,
+// so they are compared lexically.
+const sqliteNow = "strftime('%Y-%m-%d %H:%M:%f', 'now')"
+
+const sqliteTimeLayout = "2006-01-02 15:04:05.000"
+
+// Job is a single job, fetched by worker.
+type Job struct {
+	ID          int64
+	Queue       string
+	Kind        string
+	Payload     json.RawMessage
+	Attempts    int // Including the current one
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+}
+
+// Handler processes job. Returned error causes retry with backoff or dead-lettering,
+// when attempts are exhausted.
+type Handler func(ctx context.Context, job *Job) error
+
+// Queue enqueues and processes jobs, stored in single table.
+type Queue struct {
+	q         dbx.Querier
+	dialect   dbx.Dialect
+	table     string
+	tableName string
+
+	handlers map[string]Handler
+}
+
+type Option func(q *Queue)
+
+// WithTable sets jobs table name. It is quoted as single identifier.
+func WithTable(name string) Option {
+	return func(q *Queue) {
+		q.tableName = name
+	}
+}
+
+// New returns Queue over q. Workers expect q to route locking reads and writes to the primary,
+// as impls do.
+func New(q dbx.Querier, dialect dbx.Dialect, options ...Option) *Queue {
+	queue := &Queue{
+		q:         q,
+		dialect:   dialect,
+		tableName: DefaultTable,
+		handlers:  make(map[string]Handler),
+	}
+
+	for _, opt := range options {
+		opt(queue)
+	}
+	queue.table = dialect.QuoteIdentifier(queue.tableName)
+
+	return queue
+}
+
+// NewSQL returns Queue over database/sql impl.
+func NewSQL(db *sql.DB, options ...Option) *Queue {
+	return New(db.Querier(), db.Dialect(), options...)
+}
+
+// NewPgx returns Queue over pgx impl.
+func NewPgx(db *pgxpoolv5.DB, options ...Option) *Queue {
+	return New(db.Querier(), db.Dialect(), options...)
+}
+
+// Handle registers handler for jobs of kind. Handlers must be registered before Work is called.
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+type enqueueParams struct {
+	queue       string
+	runAt       time.Time
+	delay       time.Duration
+	maxAttempts int
+}
+
+type EnqueueOption func(p *enqueueParams)
+
+// InQueue sets queue name of job.
+func InQueue(queue string) EnqueueOption {
+	return func(p *enqueueParams) {
+		p.queue = queue
+	}
+}
+
+// RunAt schedules job to run not earlier than t.
+func RunAt(t time.Time) EnqueueOption {
+	return func(p *enqueueParams) {
+		p.runAt = t
+	}
+}
+
+// RunIn schedules job to run not earlier than after d. Delay is counted from the database clock.
+func RunIn(d time.Duration) EnqueueOption {
+	return func(p *enqueueParams) {
+		p.delay = d
+	}
+}
+
+// MaxAttempts sets number of attempts, after which job is dead-lettered.
+func MaxAttempts(n int) EnqueueOption {
+	return func(p *enqueueParams) {
+		p.maxAttempts = n
+	}
+}
+
+// Enqueue stores job with JSON-encoded payload and returns its id. If ctx is bound to transaction
+// (see DoTxContext and DoInTx), job is enqueued in it and becomes visible to workers on commit.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any, options ...EnqueueOption) (int64, error) {
+	params := enqueueParams{
+		queue:       DefaultQueue,
+		maxAttempts: DefaultMaxAttempts,
+	}
+	for _, opt := range options {
+		opt(&params)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return 0, errx.Wrap("marshal payload", err)
+	}
+
+	var runAt any
+	if !params.runAt.IsZero() {
+		runAt = q.timeArg(params.runAt)
+	}
+
+	var id int64
+	err = q.q.QueryRowContext(ctx, fmt.Sprintf(`INSERT INTO %s (queue, kind, payload, max_attempts, run_at)
+		VALUES (%s, %s, %s, %s, coalesce(%s, %s)) RETURNING id`,
+		q.table, q.placeholder(1), q.placeholder(2), q.placeholder(3), q.placeholder(4), q.placeholder(5),
+		q.nowPlus(q.placeholder(6))),
+		params.queue, kind, raw, params.maxAttempts, runAt, params.delay.Seconds()).Scan(&id)
+	if err != nil {
+		return 0, errx.Wrap("insert job", err)
+	}
+
+	return id, nil
+}
+
+// Requeue moves dead job back to pending state and resets its attempts.
+func (q *Queue) Requeue(ctx context.Context, id int64) error {
+	res, err := q.q.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status = %s, attempts = 0, run_at = %s,
+		locked_at = NULL, updated_at = %s WHERE id = %s AND status = %s`,
+		q.table, q.placeholder(1), q.now(), q.now(), q.placeholder(2), q.placeholder(3)), StatusPending, id, StatusDead)
+	if err != nil {
+		return errx.Wrap("requeue job", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errx.Wrap("get rows affected", err)
+	}
+
+	if n == 0 {
+		return dbx.ErrNotFound
+	}
+
+	return nil
+}
+
+func (q *Queue) placeholder(n int) string {
+	return q.dialect.PlaceholderStyle().Placeholder(n)
+}
+
+// now returns expression of the current database time.
+func (q *Queue) now() string {
+	if q.dialect == dbx.DialectSQLite {
+		return sqliteNow
+	}
+
+	return "now()"
+}
+
+// nowPlus returns expression of the current database time, shifted by seconds, passed in placeholder.
+func (q *Queue) nowPlus(placeholder string) string {
+	if q.dialect == dbx.DialectSQLite {
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%f', 'now', %s || ' seconds')", placeholder)
+	}
+
+	return fmt.Sprintf("now() + make_interval(secs => %s)", placeholder)
+}
+
+// inArgs appends values with arg and returns their placeholders, joined for IN clause.
+func (q *Queue) inArgs(arg func(v any) string, values []string) string {
+	placeholders := make([]string, 0, len(values))
+	for _, v := range values {
+		placeholders = append(placeholders, arg(v))
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// timeArg returns argument for time column. SQLite times are stored as UTC text, so they are compared
+// with sqliteNow lexically.
+func (q *Queue) timeArg(t time.Time) any {
+	if q.dialect == dbx.DialectSQLite {
+		return t.UTC().Format(sqliteTimeLayout)
+	}
+
+	return t
+}
@@ -0,0 +1,148 @@
+package jobqueue
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue(t *testing.T) {
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	q := NewSQL(db)
+	assert.Nil(t, q.Setup(ctx))
+
+	cfg := &workerConfig{
+		queues:      []string{DefaultQueue},
+		lockTimeout: time.Minute,
+		backoff:     func(attempt int) time.Duration { return 0 },
+	}
+
+	claim := func() *Job {
+		job, ok, err := q.fetch(ctx, cfg)
+		assert.Nil(t, err)
+		if !ok {
+			return nil
+		}
+		return job
+	}
+
+	count := func(status string) int {
+		var n int
+		assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM dbx_jobs WHERE status = ?", status).Scan(&n))
+		return n
+	}
+
+	t.Run("enqueue in tx", func(t *testing.T) {
+		rollbackErr := errors.New("rollback")
+		err := db.DoInTx(ctx, func(ctx context.Context) error {
+			_, err := q.Enqueue(ctx, "email", map[string]string{"to": "foo"})
+			assert.Nil(t, err)
+			return rollbackErr
+		}, nil)
+		assert.ErrorIs(t, err, rollbackErr)
+		assert.Nil(t, claim())
+
+		err = db.DoInTx(ctx, func(ctx context.Context) error {
+			_, err := q.Enqueue(ctx, "email", map[string]string{"to": "bar"})
+			return err
+		}, nil)
+		assert.Nil(t, err)
+
+		job := claim()
+		assert.NotNil(t, job)
+		assert.Equal(t, "email", job.Kind)
+		assert.JSONEq(t, `{"to":"bar"}`, string(job.Payload))
+		assert.Equal(t, 1, job.Attempts)
+		assert.False(t, job.RunAt.IsZero())
+		assert.Nil(t, claim(), "claimed job must not be claimed again")
+
+		assert.Nil(t, q.complete(ctx, cfg, job, nil))
+		assert.Equal(t, 0, count(StatusRunning))
+	})
+
+	t.Run("delayed", func(t *testing.T) {
+		id, err := q.Enqueue(ctx, "email", nil, RunIn(time.Hour))
+		assert.Nil(t, err)
+		assert.Nil(t, claim())
+
+		_, err = db.Exec("DELETE FROM dbx_jobs WHERE id = ?", id)
+		assert.Nil(t, err)
+	})
+
+	t.Run("retry and dead-letter", func(t *testing.T) {
+		id, err := q.Enqueue(ctx, "email", nil, MaxAttempts(2))
+		assert.Nil(t, err)
+
+		job := claim()
+		assert.Equal(t, id, job.ID)
+		assert.Nil(t, q.complete(ctx, cfg, job, errors.New("failed")))
+		assert.Equal(t, 1, count(StatusPending))
+
+		job = claim()
+		assert.Equal(t, 2, job.Attempts)
+		assert.Nil(t, q.complete(ctx, cfg, job, errors.New("failed")))
+		assert.Equal(t, 1, count(StatusDead))
+		assert.Nil(t, claim())
+
+		var lastError string
+		assert.Nil(t, db.QueryRow("SELECT last_error FROM dbx_jobs WHERE id = ?", id).Scan(&lastError))
+		assert.Equal(t, "failed", lastError)
+
+		assert.Nil(t, q.Requeue(ctx, id))
+		assert.ErrorIs(t, q.Requeue(ctx, id), dbx.ErrNotFound)
+
+		job = claim()
+		assert.Equal(t, 1, job.Attempts)
+		assert.Nil(t, q.complete(ctx, cfg, job, nil))
+	})
+
+	t.Run("reclaimed after lock timeout", func(t *testing.T) {
+		_, err := q.Enqueue(ctx, "email", nil)
+		assert.Nil(t, err)
+
+		stale := claim()
+		_, err = db.Exec("UPDATE dbx_jobs SET locked_at = '2000-01-01 00:00:00.000'")
+		assert.Nil(t, err)
+
+		job := claim()
+		assert.Equal(t, stale.ID, job.ID)
+		assert.Equal(t, 2, job.Attempts)
+
+		// Stale worker must not complete job, claimed by another one.
+		assert.Nil(t, q.complete(ctx, cfg, stale, nil))
+		assert.Equal(t, 1, count(StatusRunning))
+
+		assert.Nil(t, q.complete(ctx, cfg, job, nil))
+		assert.Equal(t, 0, count(StatusRunning))
+	})
+
+	t.Run("work with invalid concurrency", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		q.Handle("report", func(ctx context.Context, job *Job) error {
+			cancel()
+			return nil
+		})
+		_, err := q.Enqueue(ctx, "report", nil)
+		assert.Nil(t, err)
+
+		err = q.Work(ctx, WithConcurrency(-1), WithPollInterval(10*time.Millisecond))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,64 @@
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const postgresSchemaSQL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id           bigserial PRIMARY KEY,
+	queue        text        NOT NULL,
+	kind         text        NOT NULL,
+	payload      jsonb       NOT NULL DEFAULT '{}',
+	status       text        NOT NULL DEFAULT 'pending',
+	attempts     integer     NOT NULL DEFAULT 0,
+	max_attempts integer     NOT NULL,
+	run_at       timestamptz NOT NULL DEFAULT now(),
+	locked_at    timestamptz,
+	last_error   text,
+	created_at   timestamptz NOT NULL DEFAULT now(),
+	updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, run_at, id) WHERE status IN ('pending', 'running');
+`
+
+const sqliteSchemaSQL = `
+CREATE TABLE IF NOT EXISTS %[1]s (
+	id           integer PRIMARY KEY AUTOINCREMENT,
+	queue        text    NOT NULL,
+	kind         text    NOT NULL,
+	payload      blob    NOT NULL DEFAULT '{}',
+	status       text    NOT NULL DEFAULT 'pending',
+	attempts     integer NOT NULL DEFAULT 0,
+	max_attempts integer NOT NULL,
+	run_at       text    NOT NULL DEFAULT (%[3]s),
+	locked_at    text,
+	last_error   text,
+	created_at   text    NOT NULL DEFAULT (%[3]s),
+	updated_at   text    NOT NULL DEFAULT (%[3]s)
+);
+
+CREATE INDEX IF NOT EXISTS %[2]s ON %[1]s (queue, run_at, id) WHERE status IN ('pending', 'running');
+`
+
+// Setup creates jobs table and its index, if they do not exist.
+func (q *Queue) Setup(ctx context.Context) error {
+	var schema string
+	switch q.dialect {
+	case dbx.DialectPostgres:
+		schema = postgresSchemaSQL
+	case dbx.DialectSQLite:
+		schema = sqliteSchemaSQL
+	default:
+		return fmt.Errorf("dialect %s is not supported", q.dialect)
+	}
+
+	_, err := q.q.ExecContext(ctx, fmt.Sprintf(schema, q.table, q.dialect.QuoteIdentifier(q.tableName+"_fetch_idx"),
+		sqliteNow))
+	return errx.Wrap("create jobs table", err)
+}
@@ -0,0 +1,267 @@
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultConcurrency  = 1
+	DefaultPollInterval = time.Second
+	DefaultLockTimeout  = 5 * time.Minute
+	DefaultBaseBackoff  = time.Second
+	DefaultMaxBackoff   = time.Hour
+
+	tracerName = "github.com/ValerySidorin/corex/dbx/jobqueue"
+)
+
+type workerConfig struct {
+	queues         []string
+	concurrency    int
+	pollInterval   time.Duration
+	lockTimeout    time.Duration
+	backoff        func(attempt int) time.Duration
+	onError        func(err error)
+	tracerProvider trace.TracerProvider
+}
+
+type WorkerOption func(cfg *workerConfig)
+
+// WithQueues sets queues to fetch jobs from. DefaultQueue is used, if not set.
+func WithQueues(queues ...string) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.queues = queues
+	}
+}
+
+// WithConcurrency sets number of jobs, processed concurrently. Values less than 1 are replaced
+// with DefaultConcurrency.
+func WithConcurrency(n int) WorkerOption {
+	return func(cfg *workerConfig) {
+		if n < 1 {
+			n = DefaultConcurrency
+		}
+		cfg.concurrency = n
+	}
+}
+
+// WithPollInterval sets delay between fetches, when queue is empty.
+func WithPollInterval(interval time.Duration) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithLockTimeout sets time, after which running job is considered abandoned by crashed worker
+// and is fetched again.
+func WithLockTimeout(timeout time.Duration) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.lockTimeout = timeout
+	}
+}
+
+// WithBackoff sets delay before retry of failed job.
+func WithBackoff(backoff func(attempt int) time.Duration) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.backoff = backoff
+	}
+}
+
+// WithErrorHandler sets function, which is called with fetch, job and completion errors.
+func WithErrorHandler(onError func(err error)) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.onError = onError
+	}
+}
+
+// WithTracerProvider sets tracer provider of job spans. Global one is used by default.
+func WithTracerProvider(provider trace.TracerProvider) WorkerOption {
+	return func(cfg *workerConfig) {
+		cfg.tracerProvider = provider
+	}
+}
+
+// Work fetches and processes jobs until ctx is done. Jobs are claimed on the primary with
+// FOR UPDATE SKIP LOCKED, so any number of workers may run concurrently. Succeeded jobs are deleted,
+// failed ones are retried with backoff and dead-lettered, when attempts are exhausted.
+func (q *Queue) Work(ctx context.Context, options ...WorkerOption) error {
+	cfg := workerConfig{
+		queues:         []string{DefaultQueue},
+		concurrency:    DefaultConcurrency,
+		pollInterval:   DefaultPollInterval,
+		lockTimeout:    DefaultLockTimeout,
+		backoff:        dbx.ExponentialBackoff(DefaultBaseBackoff, DefaultMaxBackoff),
+		onError:        func(err error) {},
+		tracerProvider: otel.GetTracerProvider(),
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	tracer := cfg.tracerProvider.Tracer(tracerName)
+
+	var wg sync.WaitGroup
+	wg.Add(cfg.concurrency)
+	for i := 0; i < cfg.concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			q.workLoop(ctx, &cfg, tracer)
+		}()
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func (q *Queue) workLoop(ctx context.Context, cfg *workerConfig, tracer trace.Tracer) {
+	for ctx.Err() == nil {
+		job, ok, err := q.fetch(ctx, cfg)
+		if err != nil && ctx.Err() == nil {
+			cfg.onError(errx.Wrap("fetch job", err))
+		}
+
+		if !ok {
+			timer := time.NewTimer(cfg.pollInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			continue
+		}
+
+		q.process(ctx, cfg, tracer, job)
+	}
+}
+
+// fetch claims the next due job. Running jobs with expired lock are claimed again.
+func (q *Queue) fetch(ctx context.Context, cfg *workerConfig) (*Job, bool, error) {
+	// Arguments are appended in order of placeholders, as SQLite placeholders are positional.
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return q.placeholder(len(args))
+	}
+
+	// SQLite serializes writers, so it needs no row locks.
+	lock := "FOR UPDATE SKIP LOCKED"
+	if q.dialect == dbx.DialectSQLite {
+		lock = ""
+	}
+
+	now := q.now()
+	query := fmt.Sprintf(`UPDATE %s SET status = %s, attempts = attempts + 1, locked_at = %s, updated_at = %s
+		WHERE id = (
+			SELECT id FROM %s
+			WHERE queue IN (%s) AND run_at <= %s AND
+				(status = %s OR (status = %s AND locked_at < %s))
+			ORDER BY run_at, id
+			LIMIT 1
+			%s
+		)
+		RETURNING id, queue, kind, payload, attempts, max_attempts, run_at, created_at`,
+		q.table, arg(StatusRunning), now, now,
+		q.table, q.inArgs(arg, cfg.queues), now,
+		arg(StatusPending), arg(StatusRunning), q.nowPlus(arg(-cfg.lockTimeout.Seconds())),
+		lock)
+
+	var job Job
+	var runAt, createdAt any = &job.RunAt, &job.CreatedAt
+	var sqliteRunAt, sqliteCreatedAt string
+	if q.dialect == dbx.DialectSQLite {
+		runAt, createdAt = &sqliteRunAt, &sqliteCreatedAt
+	}
+
+	err := q.q.QueryRowContext(ctx, query, args...).
+		Scan(&job.ID, &job.Queue, &job.Kind, &job.Payload, &job.Attempts, &job.MaxAttempts, runAt, createdAt)
+	if err != nil {
+		if errors.Is(err, dbx.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	if q.dialect == dbx.DialectSQLite {
+		if job.RunAt, err = time.Parse(sqliteTimeLayout, sqliteRunAt); err != nil {
+			return nil, false, errx.Wrap("parse run_at", err)
+		}
+		if job.CreatedAt, err = time.Parse(sqliteTimeLayout, sqliteCreatedAt); err != nil {
+			return nil, false, errx.Wrap("parse created_at", err)
+		}
+	}
+
+	return &job, true, nil
+}
+
+func (q *Queue) process(ctx context.Context, cfg *workerConfig, tracer trace.Tracer, job *Job) {
+	ctx, span := tracer.Start(ctx, "jobqueue.process "+job.Kind,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.Int64("job.id", job.ID),
+			attribute.String("job.queue", job.Queue),
+			attribute.String("job.kind", job.Kind),
+			attribute.Int("job.attempt", job.Attempts),
+		))
+	defer span.End()
+
+	jobErr := q.run(ctx, job)
+	if jobErr != nil {
+		span.RecordError(jobErr)
+		span.SetStatus(codes.Error, jobErr.Error())
+		cfg.onError(fmt.Errorf("job %d (%s): %w", job.ID, job.Kind, jobErr))
+	}
+
+	// Job is completed even if ctx is done, so it is not processed again after lock timeout.
+	if err := q.complete(context.WithoutCancel(ctx), cfg, job, jobErr); err != nil {
+		span.RecordError(err)
+		cfg.onError(errx.Wrap("complete job", err))
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, job)
+}
+
+// complete deletes succeeded job, reschedules failed one or dead-letters it.
+// Job is updated only if it was not reclaimed by another worker after lock timeout,
+// which would have incremented its attempts.
+func (q *Queue) complete(ctx context.Context, cfg *workerConfig, job *Job, jobErr error) error {
+	if jobErr == nil {
+		_, err := q.q.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = %s AND status = %s AND attempts = %s`,
+			q.table, q.placeholder(1), q.placeholder(2), q.placeholder(3)), job.ID, StatusRunning, job.Attempts)
+		return errx.Wrap("delete job", err)
+	}
+
+	status, delay := StatusPending, cfg.backoff(job.Attempts)
+	if job.Attempts >= job.MaxAttempts {
+		status, delay = StatusDead, 0
+	}
+
+	_, err := q.q.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status = %s, run_at = %s, last_error = %s,
+		locked_at = NULL, updated_at = %s WHERE id = %s AND status = %s AND attempts = %s`,
+		q.table, q.placeholder(1), q.nowPlus(q.placeholder(2)), q.placeholder(3), q.now(),
+		q.placeholder(4), q.placeholder(5), q.placeholder(6)),
+		status, delay.Seconds(), jobErr.Error(), job.ID, StatusRunning, job.Attempts)
+	return errx.Wrap("update job", err)
+}
@@ -0,0 +1,282 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// ErrInvalidCursor is returned, when cursor token can not be decoded or does not match keyset columns.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// KeysetColumn is a column of keyset ordering.
+type KeysetColumn struct {
+	Name string
+	Desc bool
+}
+
+// Asc returns ascending keyset column.
+func Asc(name string) KeysetColumn {
+	return KeysetColumn{Name: name}
+}
+
+// Desc returns descending keyset column.
+func Desc(name string) KeysetColumn {
+	return KeysetColumn{Name: name, Desc: true}
+}
+
+// Page is a single page of keyset pagination. Next is empty on the last page.
+type Page[T any] struct {
+	Items []T
+	Next  string
+}
+
+// Paginator implements keyset (seek) pagination over Query. Query is wrapped into subquery,
+// so it may contain its own WHERE clause and arguments, but must not be ordered or limited.
+//
+// Keys must uniquely identify row: the last key should be a unique column (usually primary key),
+// which breaks ties of non-unique sort columns. Key names refer to columns of Query result.
+// Key columns must be NOT NULL, as NULL can not be compared in seek predicate, so Page fails on NULL keys.
+type Paginator[T any] struct {
+	Query    string
+	Args     []any
+	Keys     []KeysetColumn
+	PageSize int
+
+	// Key returns values of Keys for elem in the same order.
+	Key func(elem *T) []any
+	// Fetch executes paginated query. Impls fetch from ReadFromNodeStrategy node.
+	Fetch func(ctx context.Context, query string, args ...any) ([]T, error)
+	Style PlaceholderStyle
+}
+
+// Page fetches page, which follows cursor. Empty cursor means the first page.
+func (p *Paginator[T]) Page(ctx context.Context, cursor string) (Page[T], error) {
+	if len(p.Keys) == 0 {
+		return Page[T]{}, errors.New("no keyset columns")
+	}
+	if p.PageSize <= 0 {
+		return Page[T]{}, errors.New("page size must be positive")
+	}
+
+	var after []any
+	if cursor != "" {
+		var err error
+		after, err = DecodeCursor(p.Keys, cursor)
+		if err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	query, args := p.pageQuery(after)
+	items, err := p.Fetch(ctx, query, args...)
+	if err != nil {
+		return Page[T]{}, errx.Wrap("fetch page", err)
+	}
+
+	res := Page[T]{Items: items}
+	if len(items) > p.PageSize {
+		res.Items = items[:p.PageSize]
+
+		res.Next, err = EncodeCursor(p.Keys, p.Key(&res.Items[p.PageSize-1]))
+		if err != nil {
+			return Page[T]{}, errx.Wrap("encode cursor", err)
+		}
+	}
+
+	return res, nil
+}
+
+// pageQuery builds query of page after passed key values. One extra row is fetched to detect next page.
+// Seek predicate is expanded to (a > ?) OR (a = ? AND b > ?) ..., so it works for mixed directions.
+func (p *Paginator[T]) pageQuery(after []any) (string, []any) {
+	args := append([]any{}, p.Args...)
+
+	var b strings.Builder
+	b.WriteString("SELECT * FROM (")
+	b.WriteString(p.Query)
+	b.WriteString(") AS keyset_page")
+
+	if after != nil {
+		b.WriteString(" WHERE ")
+		for i := range p.Keys {
+			if i > 0 {
+				b.WriteString(" OR ")
+			}
+
+			b.WriteByte('(')
+			for j := 0; j <= i; j++ {
+				if j > 0 {
+					b.WriteString(" AND ")
+				}
+
+				op := " = "
+				if j == i {
+					op = " > "
+					if p.Keys[j].Desc {
+						op = " < "
+					}
+				}
+
+				args = append(args, after[j])
+				b.WriteString(p.Keys[j].Name)
+				b.WriteString(op)
+				b.WriteString(p.Style.Placeholder(len(args)))
+			}
+			b.WriteByte(')')
+		}
+	}
+
+	b.WriteString(" ORDER BY ")
+	for i, key := range p.Keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(key.Name)
+		if key.Desc {
+			b.WriteString(" DESC")
+		}
+	}
+
+	if p.Style == PlaceholderAtP {
+		fmt.Fprintf(&b, " OFFSET 0 ROWS FETCH NEXT %d ROWS ONLY", p.PageSize+1)
+	} else {
+		fmt.Fprintf(&b, " LIMIT %d", p.PageSize+1)
+	}
+
+	return b.String(), args
+}
+
+type cursorValue struct {
+	Column string          `json:"c"`
+	Desc   bool            `json:"d,omitempty"`
+	Type   string          `json:"t"`
+	Value  json.RawMessage `json:"v"`
+}
+
+// EncodeCursor encodes values of keyset columns into opaque token. Types of values are preserved, so they
+// are passed to the driver as is after decoding. Supported are integers, floats, strings, bools, []byte,
+// time.Time and driver.Valuer implementations, returning any of them. NULL values are not supported.
+func EncodeCursor(keys []KeysetColumn, values []any) (string, error) {
+	if len(values) != len(keys) {
+		return "", fmt.Errorf("expected %d key values, got %d", len(keys), len(values))
+	}
+
+	res := make([]cursorValue, 0, len(values))
+	for i, v := range values {
+		if valuer, ok := v.(driver.Valuer); ok {
+			var err error
+			if v, err = valuer.Value(); err != nil {
+				return "", errx.Wrap("get driver value", err)
+			}
+		}
+
+		var typ string
+		switch rv := reflect.ValueOf(v); rv.Kind() {
+		case reflect.Invalid:
+			return "", fmt.Errorf("keyset column %s is NULL", keys[i].Name)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			typ, v = "int", rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			typ, v = "uint", rv.Uint()
+		case reflect.Float32, reflect.Float64:
+			typ, v = "float", rv.Float()
+		case reflect.String:
+			typ, v = "string", rv.String()
+		case reflect.Bool:
+			typ, v = "bool", rv.Bool()
+		default:
+			switch tv := v.(type) {
+			case time.Time:
+				typ, v = "time", tv.Format(time.RFC3339Nano)
+			case []byte:
+				typ = "bytes"
+			default:
+				return "", fmt.Errorf("unsupported cursor value type %T", v)
+			}
+		}
+
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return "", errx.Wrap("marshal cursor value", err)
+		}
+		res = append(res, cursorValue{Column: keys[i].Name, Desc: keys[i].Desc, Type: typ, Value: raw})
+	}
+
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return "", errx.Wrap("marshal cursor", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor decodes values of keyset columns from token, produced by EncodeCursor.
+// Token must be encoded for the same columns in the same order and directions.
+func DecodeCursor(keys []KeysetColumn, cursor string) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var values []cursorValue
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if len(values) != len(keys) {
+		return nil, fmt.Errorf("%w: expected %d values, got %d", ErrInvalidCursor, len(keys), len(values))
+	}
+
+	res := make([]any, 0, len(values))
+	for i, cv := range values {
+		if cv.Column != keys[i].Name || cv.Desc != keys[i].Desc {
+			return nil, fmt.Errorf("%w: value %d is for column %s, not %s", ErrInvalidCursor, i, cv.Column, keys[i].Name)
+		}
+
+		var v any
+		switch cv.Type {
+		case "int":
+			v, err = unmarshalCursorValue[int64](cv.Value)
+		case "uint":
+			v, err = unmarshalCursorValue[uint64](cv.Value)
+		case "float":
+			v, err = unmarshalCursorValue[float64](cv.Value)
+		case "string":
+			v, err = unmarshalCursorValue[string](cv.Value)
+		case "bool":
+			v, err = unmarshalCursorValue[bool](cv.Value)
+		case "bytes":
+			v, err = unmarshalCursorValue[[]byte](cv.Value)
+		case "time":
+			var s string
+			if s, err = unmarshalCursorValue[string](cv.Value); err == nil {
+				v, err = time.Parse(time.RFC3339Nano, s)
+			}
+		default:
+			err = fmt.Errorf("unknown value type %q", cv.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+		}
+
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+func unmarshalCursorValue[T any](raw json.RawMessage) (T, error) {
+	var res T
+	err := json.Unmarshal(raw, &res)
+	return res, err
+}
@@ -0,0 +1,151 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"time"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const (
+	DefaultLeaderRetryInterval = 5 * time.Second
+	DefaultLeaderCheckInterval = time.Second
+)
+
+var (
+	// ErrLeadershipLost is a cause of leader context cancellation, when lock connection or primary is lost.
+	ErrLeadershipLost = errors.New("leadership lost")
+)
+
+// LockKey returns advisory lock key for name.
+func LockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLock is a session-scoped lock, held by dedicated connection.
+type AdvisoryLock interface {
+	// Check returns error, if lock connection is broken or its node is no longer primary.
+	Check(ctx context.Context) error
+	// Release releases lock and its connection.
+	Release(ctx context.Context) error
+}
+
+// LeaderElector runs function while advisory lock is held.
+type LeaderElector struct {
+	tryLock       func(ctx context.Context) (AdvisoryLock, bool, error)
+	retryInterval time.Duration
+	checkInterval time.Duration
+	onError       func(err error)
+}
+
+type LeaderOption func(e *LeaderElector)
+
+// WithLeaderRetryInterval sets delay between attempts to acquire leadership.
+func WithLeaderRetryInterval(interval time.Duration) LeaderOption {
+	return func(e *LeaderElector) {
+		e.retryInterval = interval
+	}
+}
+
+// WithLeaderCheckInterval sets how often lock connection and primary are checked.
+// It also bounds the duration of each check and of lock release.
+func WithLeaderCheckInterval(interval time.Duration) LeaderOption {
+	return func(e *LeaderElector) {
+		e.checkInterval = interval
+	}
+}
+
+// WithLeaderErrorHandler sets function, which is called with errors of lock acquiring and releasing.
+func WithLeaderErrorHandler(onError func(err error)) LeaderOption {
+	return func(e *LeaderElector) {
+		e.onError = onError
+	}
+}
+
+// NewLeaderElector returns LeaderElector, which uses tryLock to acquire leadership.
+// Impls provide it with NewLeaderElector method.
+func NewLeaderElector(tryLock func(ctx context.Context) (AdvisoryLock, bool, error),
+	options ...LeaderOption) *LeaderElector {
+	e := &LeaderElector{
+		tryLock:       tryLock,
+		retryInterval: DefaultLeaderRetryInterval,
+		checkInterval: DefaultLeaderCheckInterval,
+		onError:       func(err error) {},
+	}
+
+	for _, opt := range options {
+		opt(e)
+	}
+
+	return e
+}
+
+// Run campaigns for leadership until ctx is done and runs f, while leadership is held.
+// If lock connection or primary is lost, f context is canceled with ErrLeadershipLost cause
+// and Run campaigns again after f returns. Otherwise Run returns the result of f.
+func (e *LeaderElector) Run(ctx context.Context, f func(ctx context.Context) error) error {
+	for {
+		lock, ok, err := e.tryLock(ctx)
+		if err != nil {
+			e.onError(errx.Wrap("try lock", err))
+		}
+
+		if ok {
+			lost, err := e.lead(ctx, lock, f)
+			if !lost {
+				return err
+			}
+			if ctx.Err() == nil {
+				// Retry immediately, leadership may be regained on the new primary.
+				continue
+			}
+		}
+
+		timer := time.NewTimer(e.retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (e *LeaderElector) lead(ctx context.Context, lock AdvisoryLock, f func(ctx context.Context) error) (bool, error) {
+	leaderCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(e.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), e.checkInterval)
+			if releaseErr := lock.Release(releaseCtx); releaseErr != nil {
+				e.onError(errx.Wrap("release lock", releaseErr))
+			}
+			releaseCancel()
+
+			lost := errors.Is(context.Cause(leaderCtx), ErrLeadershipLost)
+			return lost, err
+		case <-ticker.C:
+			checkCtx, checkCancel := context.WithTimeout(leaderCtx, e.checkInterval)
+			err := lock.Check(checkCtx)
+			checkCancel()
+			if err != nil && leaderCtx.Err() == nil {
+				e.onError(errx.Wrap("check lock", err))
+				cancel(errors.Join(ErrLeadershipLost, err))
+			}
+		}
+	}
+}
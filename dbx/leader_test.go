@@ -0,0 +1,55 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testLock struct {
+	checkErr error
+	released atomic.Bool
+}
+
+func (l *testLock) Check(ctx context.Context) error {
+	return l.checkErr
+}
+
+func (l *testLock) Release(ctx context.Context) error {
+	l.released.Store(true)
+	return nil
+}
+
+func TestLeaderElector(t *testing.T) {
+	var locks []*testLock
+	e := NewLeaderElector(func(ctx context.Context) (AdvisoryLock, bool, error) {
+		lock := &testLock{}
+		if len(locks) == 0 {
+			// The first leadership is lost.
+			lock.checkErr = errors.New("conn closed")
+		}
+		locks = append(locks, lock)
+		return lock, true, nil
+	}, WithLeaderCheckInterval(time.Millisecond))
+
+	var runs int
+	err := e.Run(context.Background(), func(ctx context.Context) error {
+		runs++
+		if runs == 1 {
+			<-ctx.Done()
+			assert.ErrorIs(t, context.Cause(ctx), ErrLeadershipLost)
+			return ctx.Err()
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Len(t, locks, 2)
+	assert.True(t, locks[0].released.Load())
+	assert.True(t, locks[1].released.Load())
+}
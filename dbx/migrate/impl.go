@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/impl/pgxpoolv5"
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	"github.com/ValerySidorin/corex/errx"
+	"github.com/jackc/pgx/v5"
+)
+
+func newMigrator(q dbx.Querier, dialect dbx.Dialect, fsys fs.FS, options ...Option) *Migrator {
+	m := &Migrator{
+		q:                q,
+		dialect:          dialect,
+		fsys:             fsys,
+		tableName:        DefaultTable,
+		lockPollInterval: DefaultLockPollInterval,
+		lockTimeout:      DefaultLockTimeout,
+	}
+	m.lock = m.tableLock
+
+	for _, opt := range options {
+		opt(m)
+	}
+	m.table = dialect.QuoteIdentifier(m.tableName)
+
+	return m
+}
+
+// NewSQL returns Migrator over database/sql impl. Reads are routed to WriteToNodeStrategy node,
+// so applied migrations are not read from lagging standby.
+// MySQL DSN must allow multiple statements, if migration files contain them.
+func NewSQL(db *sql.DB, fsys fs.FS, options ...Option) *Migrator {
+	db = db.WithReadFromNodeStrategy(db.WriteToNodeStrategy)
+
+	m := newMigrator(db.Querier(), db.Dialect(), fsys, options...)
+	m.inTx = func(ctx context.Context, f func(ctx context.Context) error) error {
+		if m.dialect == dbx.DialectMySQL {
+			// DDL is committed implicitly in MySQL.
+			return f(ctx)
+		}
+
+		return db.DoInTx(ctx, f, nil)
+	}
+
+	if m.dialect == dbx.DialectPostgres {
+		m.lock = func(ctx context.Context) (func(ctx context.Context) error, error) {
+			lock, err := db.AcquireLock(ctx, dbx.LockKey(m.tableName))
+			if err != nil {
+				return nil, err
+			}
+
+			return lock.Release, nil
+		}
+	}
+
+	return m
+}
+
+// NewPgx returns Migrator over pgx impl. Reads are routed to WriteToNodeStrategy node,
+// so applied migrations are not read from lagging standby.
+func NewPgx(db *pgxpoolv5.DB, fsys fs.FS, options ...Option) *Migrator {
+	db = db.WithReadFromNodeStrategy(db.WriteToNodeStrategy)
+
+	m := newMigrator(db.Querier(), db.Dialect(), fsys, options...)
+	m.inTx = func(ctx context.Context, f func(ctx context.Context) error) error {
+		return db.DoInTx(ctx, f, pgx.TxOptions{})
+	}
+	m.lock = func(ctx context.Context) (func(ctx context.Context) error, error) {
+		lock, err := db.AcquireLock(ctx, dbx.LockKey(m.tableName))
+		if err != nil {
+			return nil, err
+		}
+
+		return lock.Release, nil
+	}
+
+	return m
+}
+
+// SQLStartupHook returns database/sql impl startup hook, which applies pending migrations,
+// so service migrates database before serving.
+func SQLStartupHook(fsys fs.FS, options ...Option) sql.StartupHook {
+	return func(ctx context.Context, db *sql.DB) error {
+		_, err := NewSQL(db, fsys, options...).Up(ctx)
+		return errx.Wrap("migrate up", err)
+	}
+}
+
+// PgxStartupHook returns pgx impl startup hook, which applies pending migrations,
+// so service migrates database before serving.
+func PgxStartupHook(fsys fs.FS, options ...Option) pgxpoolv5.StartupHook {
+	return func(ctx context.Context, db *pgxpoolv5.DB) error {
+		_, err := NewPgx(db, fsys, options...).Up(ctx)
+		return errx.Wrap("migrate up", err)
+	}
+}
@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const (
+	DefaultLockPollInterval = time.Second
+	DefaultLockTimeout      = 15 * time.Minute
+)
+
+// tableLock acquires lock by inserting single row into lock table. Holder refreshes lock time
+// with heartbeat, so lock, which was not refreshed within lock timeout, is considered abandoned
+// by crashed runner and is taken over. Lock time is taken from database clock, so clock skew
+// between runners does not matter.
+func (m *Migrator) tableLock(ctx context.Context) (func(ctx context.Context) error, error) {
+	table := m.dialect.QuoteIdentifier(m.tableName + "_lock")
+
+	query := `CREATE TABLE IF NOT EXISTS %[1]s (id integer PRIMARY KEY, locked_at %[2]s NOT NULL)`
+	timestamp := "timestamp"
+	switch m.dialect {
+	case dbx.DialectMySQL:
+		timestamp = "datetime(6)"
+	case dbx.DialectSQLServer:
+		query = `IF OBJECT_ID(N'%[3]s', N'U') IS NULL CREATE TABLE %[1]s (id integer PRIMARY KEY, locked_at %[2]s NOT NULL)`
+		timestamp = "datetime2"
+	}
+	if _, err := m.q.ExecContext(ctx, fmt.Sprintf(query, table, timestamp, quoteString(table))); err != nil {
+		return nil, errx.Wrap("create lock table", err)
+	}
+
+	now, staleBefore := m.lockTimeExprs()
+	for {
+		_, err := m.q.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1 AND locked_at < %s",
+			table, staleBefore), int64(math.Ceil(m.lockTimeout.Seconds())))
+		if err != nil {
+			return nil, errx.Wrap("delete abandoned lock", err)
+		}
+
+		_, insertErr := m.q.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (id, locked_at) VALUES (1, %s)", table, now))
+		if insertErr == nil {
+			break
+		}
+
+		// Insert fails with duplicate key, if lock is held. Other errors are returned.
+		var held int
+		if err := m.q.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = 1", table)).
+			Scan(&held); err != nil || held == 0 {
+			return nil, errx.Wrap("insert lock", insertErr)
+		}
+
+		timer := time.NewTimer(m.lockPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(context.WithoutCancel(ctx))
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+
+		ticker := time.NewTicker(m.lockTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+				// Failed heartbeat is retried on the next tick. Lock is taken over only after lock timeout.
+				_, _ = m.q.ExecContext(heartbeatCtx, fmt.Sprintf("UPDATE %s SET locked_at = %s WHERE id = 1",
+					table, now))
+			}
+		}
+	}()
+
+	return func(ctx context.Context) error {
+		stopHeartbeat()
+		<-heartbeatDone
+
+		_, err := m.q.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = 1", table))
+		return errx.Wrap("delete lock", err)
+	}, nil
+}
+
+// lockTimeExprs returns expressions of the current database time and of the time, which is earlier
+// by number of seconds, passed as the first argument.
+func (m *Migrator) lockTimeExprs() (now, staleBefore string) {
+	p := m.dialect.PlaceholderStyle().Placeholder(1)
+
+	switch m.dialect {
+	case dbx.DialectPostgres:
+		return "LOCALTIMESTAMP", "LOCALTIMESTAMP - make_interval(secs => " + p + ")"
+	case dbx.DialectMySQL:
+		return "CURRENT_TIMESTAMP(6)", "CURRENT_TIMESTAMP(6) - INTERVAL " + p + " SECOND"
+	case dbx.DialectSQLServer:
+		return "SYSDATETIME()", "DATEADD(second, -" + p + ", SYSDATETIME())"
+	default:
+		return "CURRENT_TIMESTAMP", "datetime('now', '-' || " + p + " || ' seconds')"
+	}
+}
@@ -0,0 +1,406 @@
+// Package migrate applies versioned SQL migrations to the cluster primary.
+//
+// Migrations are read from fs.FS files, named <version>_<name>.up.sql and <version>_<name>.down.sql.
+// Every migration is applied in its own transaction (except for MySQL, which has no transactional DDL)
+// and recorded with checksum of its up file. Concurrent runners are serialized with advisory lock
+// on Postgres or with lock table on other databases.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const DefaultTable = "dbx_migrations"
+
+var (
+	// ErrChecksumMismatch is returned, when applied migration file was changed.
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrNoDown is returned, when migration to revert has no down file.
+	ErrNoDown = errors.New("migration has no down file")
+)
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned migration.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of Up
+}
+
+// Status is a migration with its applied state.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations from fs.FS.
+type Migrator struct {
+	q       dbx.Querier
+	dialect dbx.Dialect
+	inTx    func(ctx context.Context, f func(ctx context.Context) error) error
+	lock    func(ctx context.Context) (unlock func(ctx context.Context) error, err error)
+
+	fsys      fs.FS
+	table     string
+	tableName string
+	dryRun    bool
+
+	lockPollInterval time.Duration
+	lockTimeout      time.Duration
+}
+
+type Option func(m *Migrator)
+
+// WithTable sets name of table, which records applied migrations. It is quoted as single identifier.
+// Lock table is named after it with _lock suffix.
+func WithTable(name string) Option {
+	return func(m *Migrator) {
+		m.tableName = name
+	}
+}
+
+// WithDryRun makes Up and Down return migrations, which would be applied or reverted,
+// without executing them. Dry run does not create migrations table.
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// WithLockTimeout sets time, after which lock table lock, which was not refreshed by holder's heartbeat,
+// is considered abandoned by crashed runner. Non-positive timeouts are replaced with DefaultLockTimeout,
+// positive ones are rounded up to a second, since lock time is compared with second precision.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(m *Migrator) {
+		switch {
+		case timeout <= 0:
+			timeout = DefaultLockTimeout
+		case timeout < time.Second:
+			timeout = time.Second
+		}
+		m.lockTimeout = timeout
+	}
+}
+
+// Migrations parses migrations from fsys root directory, sorted by version.
+func Migrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errx.Wrap("read migrations dir", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse version of %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, errx.Wrap("read migration file", err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	res := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d has no up file", m.Version)
+		}
+		res = append(res, *m)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+
+	return res, nil
+}
+
+// Status returns all migrations with their applied state. It does not create migrations table.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Migrations(m.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Migration: migration}
+		if a, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = a.appliedAt
+		}
+		res = append(res, status)
+	}
+
+	return res, nil
+}
+
+// Up applies all pending migrations in order of versions and returns them.
+// Checksums of applied migrations are verified before applying.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	migrations, err := Migrations(m.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Migration
+	err = m.locked(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if a, ok := applied[migration.Version]; ok {
+				if a.checksum != migration.Checksum {
+					return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+				}
+				continue
+			}
+
+			if !m.dryRun {
+				if err := m.apply(ctx, migration); err != nil {
+					return err
+				}
+			}
+			res = append(res, migration)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// Down reverts up to steps last applied migrations in reverse order and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := Migrations(m.fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Migration
+	err = m.locked(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(res) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, migration.Version, migration.Name)
+			}
+
+			if !m.dryRun {
+				if err := m.revert(ctx, migration); err != nil {
+					return err
+				}
+			}
+			res = append(res, migration)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// locked runs f while lock is held. Dry run is not locked and does not create migrations table.
+func (m *Migrator) locked(ctx context.Context, f func(ctx context.Context) error) error {
+	if m.dryRun {
+		return f(ctx)
+	}
+
+	if err := m.setup(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return errx.Wrap("lock migrations", err)
+	}
+
+	err = f(ctx)
+	if unlockErr := unlock(context.WithoutCancel(ctx)); unlockErr != nil {
+		err = errors.Join(err, errx.Wrap("unlock migrations", unlockErr))
+	}
+
+	return err
+}
+
+func (m *Migrator) apply(ctx context.Context, migration Migration) error {
+	err := m.inTx(ctx, func(ctx context.Context) error {
+		if _, err := m.q.ExecContext(ctx, migration.Up); err != nil {
+			return err
+		}
+
+		style := m.dialect.PlaceholderStyle()
+		_, err := m.q.ExecContext(ctx, fmt.Sprintf(
+			"INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)", m.table,
+			style.Placeholder(1), style.Placeholder(2), style.Placeholder(3), style.Placeholder(4)),
+			migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+		return errx.Wrap("record migration", err)
+	})
+
+	return errx.Wrap(fmt.Sprintf("apply migration %d_%s", migration.Version, migration.Name), err)
+}
+
+func (m *Migrator) revert(ctx context.Context, migration Migration) error {
+	err := m.inTx(ctx, func(ctx context.Context) error {
+		if _, err := m.q.ExecContext(ctx, migration.Down); err != nil {
+			return err
+		}
+
+		_, err := m.q.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = %s",
+			m.table, m.dialect.PlaceholderStyle().Placeholder(1)), migration.Version)
+		return errx.Wrap("delete migration record", err)
+	})
+
+	return errx.Wrap(fmt.Sprintf("revert migration %d_%s", migration.Version, migration.Name), err)
+}
+
+type appliedMigration struct {
+	version   int64
+	checksum  string
+	appliedAt time.Time
+}
+
+// applied returns applied migrations by version. Missing migrations table means, that none are applied.
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int64]appliedMigration{}, nil
+	}
+
+	rows, err := m.q.QueryContext(ctx, fmt.Sprintf("SELECT version, checksum, applied_at FROM %s", m.table))
+	if err != nil {
+		return nil, errx.Wrap("query applied migrations", err)
+	}
+	defer rows.Close()
+
+	list, err := dbx.Scan(rows, func(a *appliedMigration) []interface{} {
+		return []interface{}{&a.version, &a.checksum, &a.appliedAt}
+	})
+	if err != nil {
+		return nil, errx.Wrap("scan applied migrations", err)
+	}
+
+	res := make(map[int64]appliedMigration, len(list))
+	for _, a := range list {
+		res[a.version] = a
+	}
+
+	return res, nil
+}
+
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	var query string
+	switch m.dialect {
+	case dbx.DialectPostgres:
+		query = "SELECT CASE WHEN to_regclass($1) IS NULL THEN 0 ELSE 1 END"
+	case dbx.DialectMySQL:
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	case dbx.DialectSQLServer:
+		query = "SELECT CASE WHEN OBJECT_ID(@p1, N'U') IS NULL THEN 0 ELSE 1 END"
+	default:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	}
+
+	// to_regclass and OBJECT_ID parse their argument as identifier, others compare raw name.
+	name := m.tableName
+	if m.dialect == dbx.DialectPostgres || m.dialect == dbx.DialectSQLServer {
+		name = m.table
+	}
+
+	var exists int
+	if err := m.q.QueryRowContext(ctx, query, name).Scan(&exists); err != nil {
+		return false, errx.Wrap("check migrations table", err)
+	}
+
+	return exists > 0, nil
+}
+
+func (m *Migrator) setup(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS %[1]s (
+		version    bigint PRIMARY KEY,
+		name       varchar(255) NOT NULL,
+		checksum   varchar(64) NOT NULL,
+		applied_at %[2]s NOT NULL
+	)`
+	timestamp := "timestamp"
+	switch m.dialect {
+	case dbx.DialectPostgres:
+		timestamp = "timestamptz"
+	case dbx.DialectMySQL:
+		timestamp = "datetime(6)"
+	case dbx.DialectSQLServer:
+		query = `IF OBJECT_ID(N'%[3]s', N'U') IS NULL CREATE TABLE %[1]s (
+			version    bigint PRIMARY KEY,
+			name       nvarchar(255) NOT NULL,
+			checksum   varchar(64) NOT NULL,
+			applied_at %[2]s NOT NULL
+		)`
+		timestamp = "datetime2"
+	}
+
+	_, err := m.q.ExecContext(ctx, fmt.Sprintf(query, m.table, timestamp, quoteString(m.table)))
+	return errx.Wrap("create migrations table", err)
+}
+
+// quoteString escapes s to be put into string literal.
+func quoteString(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -0,0 +1,149 @@
+package migrate
+
+import (
+	"context"
+	stdsql "database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrator(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id integer PRIMARY KEY);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"2_add_name.up.sql":       {Data: []byte("ALTER TABLE users ADD COLUMN name text;")},
+		"2_add_name.down.sql":     {Data: []byte("ALTER TABLE users DROP COLUMN name;")},
+		"README.md":               {Data: []byte("ignored")},
+	}
+
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		},
+		sql.WithStartupHook(SQLStartupHook(fsys)))
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	_, err = db.Exec("INSERT INTO users (id, name) VALUES (1, 'foo')")
+	assert.Nil(t, err)
+
+	m := NewSQL(db, fsys)
+	applied, err := m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := NewSQL(db, fsys, WithDryRun()).Down(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, 1)
+
+	reverted, err = m.Down(ctx, 1)
+	assert.Nil(t, err)
+	assert.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+
+	status, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.True(t, status[0].Applied)
+	assert.False(t, status[1].Applied)
+
+	fsys["1_create_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id bigint PRIMARY KEY);")}
+	_, err = m.Up(ctx)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+func TestMigratorDryRun(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id integer PRIMARY KEY);")},
+	}
+
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	applied, err := NewSQL(db, fsys, WithDryRun()).Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 1)
+
+	var tables int
+	assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables))
+	assert.Equal(t, 0, tables)
+}
+
+func TestMigratorLock(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id integer PRIMARY KEY);")},
+	}
+
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	_, err = db.Exec("CREATE TABLE dbx_migrations_lock (id integer PRIMARY KEY, locked_at timestamp NOT NULL)")
+	assert.Nil(t, err)
+
+	t.Run("held", func(t *testing.T) {
+		_, err = db.Exec("INSERT INTO dbx_migrations_lock (id, locked_at) VALUES (1, CURRENT_TIMESTAMP)")
+		assert.Nil(t, err)
+
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		_, err := NewSQL(db, fsys, WithLockTimeout(time.Minute)).Up(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		_, err = db.Exec("UPDATE dbx_migrations_lock SET locked_at = '2000-01-01 00:00:00'")
+		assert.Nil(t, err)
+
+		applied, err := NewSQL(db, fsys, WithLockTimeout(time.Minute)).Up(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, applied, 1)
+
+		var held int
+		assert.Nil(t, db.QueryRow("SELECT COUNT(*) FROM dbx_migrations_lock").Scan(&held))
+		assert.Equal(t, 0, held)
+	})
+}
+
+func TestMigratorTable(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id integer PRIMARY KEY);")},
+	}
+
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	m := NewSQL(db, fsys, WithTable(`schema "migrations"`), WithLockTimeout(0))
+	applied, err := m.Up(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, applied, 1)
+
+	status, err := m.Status(ctx)
+	assert.Nil(t, err)
+	assert.True(t, status[0].Applied)
+
+	var count int
+	assert.Nil(t, db.QueryRow(`SELECT COUNT(*) FROM "schema ""migrations"""`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
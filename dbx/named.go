@@ -0,0 +1,221 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PlaceholderStyle defines, how positional placeholders are written by the driver.
+type PlaceholderStyle int
+
+const (
+	// PlaceholderQuestion is `?` style (MySQL, SQLite).
+	PlaceholderQuestion PlaceholderStyle = iota
+	// PlaceholderDollar is `$1` style (Postgres).
+	PlaceholderDollar
+	// PlaceholderAtP is `@p1` style (SQL Server).
+	PlaceholderAtP
+)
+
+// BindNamed rewrites `:name` and `@name` placeholders in query to positional ones of passed style
+// and returns arguments in matching order. Arguments are taken from map with string keys or from struct
+// (or pointer to struct) fields, mapped by `db` tags the same way as in StructPointers.
+// Placeholders inside string literals (including Postgres `E'...'` and dollar-quoted ones), quoted identifiers
+// and comments are left as is, as well as `::` casts, `@@` variables and names, which follow identifier or `[`
+// (e.g. array slices `arr[a:b]`).
+func BindNamed(query string, arg any, style PlaceholderStyle) (string, []any, error) {
+	lookup, err := namedArgLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b       strings.Builder
+		args    []any
+		indexes = make(map[string]int)
+	)
+	b.Grow(len(query))
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		afterName := i > 0 && (isNamePart(query[i-1]) || query[i-1] == '[')
+
+		switch {
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'' && !afterName:
+			end := skipEscaped(query, i+1)
+			b.WriteString(query[i:end])
+			i = end - 1
+		case c == '$' && !afterName && dollarTag(query[i:]) != "":
+			tag := dollarTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				end = len(query)
+			} else {
+				end += i + 2*len(tag)
+			}
+			b.WriteString(query[i:end])
+			i = end - 1
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			b.WriteString(query[i:end])
+			i = end - 1
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case (c == ':' || c == '@') && i+1 < len(query) && query[i+1] == c:
+			b.WriteString(query[i : i+2])
+			i++
+		case (c == ':' || c == '@') && i+1 < len(query) && isNameStart(query[i+1]) && !afterName:
+			end := i + 1
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			i = end - 1
+
+			if idx, ok := indexes[name]; ok && style != PlaceholderQuestion {
+				b.WriteString(style.Placeholder(idx))
+				continue
+			}
+
+			v, ok := lookup(name)
+			if !ok {
+				return "", nil, fmt.Errorf("named parameter %q is not found in %T", name, arg)
+			}
+
+			args = append(args, v)
+			indexes[name] = len(args)
+			b.WriteString(style.Placeholder(len(args)))
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), args, nil
+}
+
+func namedArgLookup(arg any) (func(name string) (any, bool), error) {
+	if m, ok := arg.(map[string]any); ok {
+		return func(name string) (any, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, fmt.Errorf("named arguments are nil %s", v.Type())
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return func(name string) (any, bool) {
+			res := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !res.IsValid() {
+				return nil, false
+			}
+			return res.Interface(), true
+		}, nil
+	case v.Kind() == reflect.Struct:
+		plan := getStructPlan(v.Type())
+		return func(name string) (any, bool) {
+			index, ok := plan.fields[name]
+			if !ok {
+				return nil, false
+			}
+
+			field, err := v.FieldByIndexErr(index)
+			if err != nil {
+				// Field of nil embedded struct pointer.
+				return nil, true
+			}
+			return field.Interface(), true
+		}, nil
+	default:
+		return nil, fmt.Errorf("named arguments must be map or struct, got %T", arg)
+	}
+}
+
+// Placeholder returns positional placeholder for 1-based argument index.
+func (s PlaceholderStyle) Placeholder(idx int) string {
+	switch s {
+	case PlaceholderDollar:
+		return "$" + strconv.Itoa(idx)
+	case PlaceholderAtP:
+		return "@p" + strconv.Itoa(idx)
+	default:
+		return "?"
+	}
+}
+
+// skipQuoted returns index right after the closing quote. Doubled quotes are treated as escaped ones.
+func skipQuoted(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] == quote {
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(query)
+}
+
+// skipEscaped returns index right after the closing quote of `E'...'` string, where quotes may be escaped
+// with backslash as well.
+func skipEscaped(query string, start int) int {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case query[i] == '\\':
+			i++
+		case query[i] == '\'' && i+1 < len(query) && query[i+1] == '\'':
+			i++
+		case query[i] == '\'':
+			return i + 1
+		}
+	}
+
+	return len(query)
+}
+
+// dollarTag returns opening `$tag$` or `$$` of dollar-quoted string at the start of query, or empty string.
+func dollarTag(query string) string {
+	for i := 1; i < len(query); i++ {
+		switch {
+		case query[i] == '$':
+			return query[:i+1]
+		case i == 1 && !isNameStart(query[i]), !isNamePart(query[i]):
+			return ""
+		}
+	}
+
+	return ""
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
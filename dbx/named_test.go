@@ -0,0 +1,58 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindNamed(t *testing.T) {
+	type base struct {
+		ID int
+	}
+	type user struct {
+		base
+		Name  string `db:"user_name"`
+		Email string
+	}
+
+	query := `update users set name = :user_name, email = @email, data = '{"a": ":x"}'::jsonb ` +
+		`where id = :id and name <> :user_name -- :comment`
+	arg := user{base: base{ID: 1}, Name: "foo", Email: "foo@bar"}
+
+	t.Run("dollar", func(t *testing.T) {
+		res, args, err := BindNamed(query, arg, PlaceholderDollar)
+		assert.Nil(t, err)
+		assert.Equal(t, `update users set name = $1, email = $2, data = '{"a": ":x"}'::jsonb `+
+			`where id = $3 and name <> $1 -- :comment`, res)
+		assert.Equal(t, []any{"foo", "foo@bar", 1}, args)
+	})
+
+	t.Run("question", func(t *testing.T) {
+		res, args, err := BindNamed("select * from users where id = :id or parent_id = :id",
+			map[string]any{"id": 1}, PlaceholderQuestion)
+		assert.Nil(t, err)
+		assert.Equal(t, "select * from users where id = ? or parent_id = ?", res)
+		assert.Equal(t, []any{1, 1}, args)
+	})
+
+	t.Run("at p", func(t *testing.T) {
+		res, args, err := BindNamed("select @@rowcount, :id", &arg, PlaceholderAtP)
+		assert.Nil(t, err)
+		assert.Equal(t, "select @@rowcount, @p1", res)
+		assert.Equal(t, []any{1}, args)
+	})
+
+	t.Run("postgres literals", func(t *testing.T) {
+		query := `select arr[:lo:hi], arr[lo:hi], $$:body$$, $fn$ select ':x' $fn$, E'it\'s :esc', :id`
+		res, args, err := BindNamed(query, arg, PlaceholderDollar)
+		assert.Nil(t, err)
+		assert.Equal(t, `select arr[:lo:hi], arr[lo:hi], $$:body$$, $fn$ select ':x' $fn$, E'it\'s :esc', $1`, res)
+		assert.Equal(t, []any{1}, args)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, _, err := BindNamed("select :unknown", arg, PlaceholderDollar)
+		assert.NotNil(t, err)
+	})
+}
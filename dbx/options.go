@@ -39,6 +39,42 @@ func WithNodeWaitTimeout[T any](timeout time.Duration) Option[T] {
 	}
 }
 
+func WithReadRetryPolicy[T any](policy RetryPolicy) Option[T] {
+	return func(db *DB[T]) {
+		db.ReadRetryPolicy = policy
+	}
+}
+
+func WithTxRetryPolicy[T any](policy TxRetryPolicy) Option[T] {
+	return func(db *DB[T]) {
+		db.TxRetryPolicy = policy
+	}
+}
+
+func WithStructScanMode[T any](mode StructScanMode) Option[T] {
+	return func(db *DB[T]) {
+		db.StructScanMode = mode
+	}
+}
+
+func WithTenantScope[T any](scope *TenantScope) Option[T] {
+	return func(db *DB[T]) {
+		db.TenantScope = scope
+	}
+}
+
+func WithHedgedReads[T any](policy HedgePolicy) Option[T] {
+	return func(db *DB[T]) {
+		db.Hedger = NewHedger(policy)
+	}
+}
+
+func WithTracer[T any](tracer Tracer[T]) Option[T] {
+	return func(db *DB[T]) {
+		db.Tracer = tracer
+	}
+}
+
 func WithClusterOptions[T any](options ...cluster.ClusterOption[T]) Option[T] {
 	return func(db *DB[T]) {
 		db.clusterOpts = append(db.clusterOpts, options...)
@@ -0,0 +1,216 @@
+// Package outbox implements transactional outbox. Repos write messages to outbox table in the same
+// transaction with their changes, and relay publishes them to a pluggable Publisher in commit order
+// of messages with the same key.
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/impl/pgxpoolv5"
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const DefaultTable = "dbx_outbox"
+
+// Message is a single outbox message.
+type Message struct {
+	ID        int64
+	Topic     string
+	Key       string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Outbox writes and relays messages of single outbox table.
+type Outbox struct {
+	q         dbx.Querier
+	dialect   dbx.Dialect
+	table     string
+	tableName string
+}
+
+type Option func(o *Outbox)
+
+// WithTable sets outbox table name. It is quoted as single identifier.
+func WithTable(name string) Option {
+	return func(o *Outbox) {
+		o.tableName = name
+	}
+}
+
+// New returns Outbox over q. Relay expects q to route reads to the primary.
+func New(q dbx.Querier, dialect dbx.Dialect, options ...Option) *Outbox {
+	o := &Outbox{
+		q:         q,
+		dialect:   dialect,
+		tableName: DefaultTable,
+	}
+
+	for _, opt := range options {
+		opt(o)
+	}
+	o.table = dialect.QuoteIdentifier(o.tableName)
+
+	return o
+}
+
+// NewSQL returns Outbox over database/sql impl. Reads are routed to WriteToNodeStrategy node,
+// so relay does not read delivered messages again from lagging standby.
+func NewSQL(db *sql.DB, options ...Option) *Outbox {
+	db = db.WithReadFromNodeStrategy(db.WriteToNodeStrategy)
+	return New(db.Querier(), db.Dialect(), options...)
+}
+
+// NewPgx returns Outbox over pgx impl. Reads are routed to WriteToNodeStrategy node,
+// so relay does not read delivered messages again from lagging standby.
+func NewPgx(db *pgxpoolv5.DB, options ...Option) *Outbox {
+	db = db.WithReadFromNodeStrategy(db.WriteToNodeStrategy)
+	return New(db.Querier(), db.Dialect(), options...)
+}
+
+// Setup creates outbox table, if it does not exist. In MySQL table of message keys is created as well,
+// its rows are locked by Write.
+func (o *Outbox) Setup(ctx context.Context) error {
+	var query string
+	switch o.dialect {
+	case dbx.DialectPostgres:
+		query = `CREATE TABLE IF NOT EXISTS %s (
+			id           bigserial PRIMARY KEY,
+			topic        text NOT NULL,
+			msg_key      text NOT NULL DEFAULT '',
+			payload      bytea,
+			created_at   timestamptz NOT NULL DEFAULT now(),
+			delivered_at timestamptz
+		)`
+	case dbx.DialectMySQL:
+		query = `CREATE TABLE IF NOT EXISTS %s (
+			id           bigint AUTO_INCREMENT PRIMARY KEY,
+			topic        varchar(255) NOT NULL,
+			msg_key      varchar(255) NOT NULL DEFAULT '',
+			payload      longblob,
+			created_at   timestamp(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			delivered_at timestamp(6) NULL
+		)`
+	case dbx.DialectSQLServer:
+		query = `IF OBJECT_ID(N'%[1]s', N'U') IS NULL CREATE TABLE %[1]s (
+			id           bigint IDENTITY PRIMARY KEY,
+			topic        nvarchar(255) NOT NULL,
+			msg_key      nvarchar(255) NOT NULL DEFAULT '',
+			payload      varbinary(max),
+			created_at   datetime2 NOT NULL DEFAULT SYSUTCDATETIME(),
+			delivered_at datetime2 NULL
+		)`
+	default:
+		query = `CREATE TABLE IF NOT EXISTS %s (
+			id           integer PRIMARY KEY AUTOINCREMENT,
+			topic        text NOT NULL,
+			msg_key      text NOT NULL DEFAULT '',
+			payload      blob,
+			created_at   timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			delivered_at timestamp
+		)`
+	}
+
+	if _, err := o.q.ExecContext(ctx, fmt.Sprintf(query, o.table)); err != nil {
+		return errx.Wrap("create outbox table", err)
+	}
+
+	if o.dialect == dbx.DialectMySQL {
+		_, err := o.q.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (msg_key varchar(255) PRIMARY KEY)",
+			o.keysTable()))
+		return errx.Wrap("create outbox keys table", err)
+	}
+
+	return nil
+}
+
+// Write stores message in outbox. It must be called with ctx, bound to transaction
+// (see DoTxContext and DoInTx), so message is stored atomically with other changes.
+//
+// Message ids are not assigned in commit order, so relay could publish message before another one
+// with lower id, which is committed later. To prevent it, Write locks key until transaction ends,
+// so messages with the same key are committed in order of their ids. Messages with empty key are not locked
+// and may be published in any order.
+func (o *Outbox) Write(ctx context.Context, topic, key string, payload []byte) error {
+	if err := o.lockKey(ctx, key); err != nil {
+		return errx.Wrap("lock message key", err)
+	}
+
+	style := o.dialect.PlaceholderStyle()
+	_, err := o.q.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (topic, msg_key, payload) VALUES (%s, %s, %s)",
+		o.table, style.Placeholder(1), style.Placeholder(2), style.Placeholder(3)), topic, key, payload)
+	return errx.Wrap("insert outbox message", err)
+}
+
+// lockKey takes transaction-level lock on key. SQLite serializes writers, so it needs no locks.
+func (o *Outbox) lockKey(ctx context.Context, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	var err error
+	switch o.dialect {
+	case dbx.DialectPostgres:
+		_, err = o.q.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", dbx.LockKey(o.tableName+"/"+key))
+	case dbx.DialectMySQL:
+		_, err = o.q.ExecContext(ctx, fmt.Sprintf(
+			"INSERT INTO %s (msg_key) VALUES (?) ON DUPLICATE KEY UPDATE msg_key = msg_key", o.keysTable()), key)
+	case dbx.DialectSQLServer:
+		_, err = o.q.ExecContext(ctx, `DECLARE @res int;
+			EXEC @res = sp_getapplock @Resource = @p1, @LockMode = 'Exclusive', @LockOwner = 'Transaction';
+			IF @res < 0 THROW 50000, 'sp_getapplock failed', 1;`, o.tableName+"/"+key)
+	}
+
+	return err
+}
+
+func (o *Outbox) keysTable() string {
+	return o.dialect.QuoteIdentifier(o.tableName + "_keys")
+}
+
+// pending returns up to limit undelivered messages in order of writing.
+func (o *Outbox) pending(ctx context.Context, limit int) ([]Message, error) {
+	query := fmt.Sprintf("SELECT id, topic, msg_key, payload, created_at FROM %s "+
+		"WHERE delivered_at IS NULL ORDER BY id LIMIT %d", o.table, limit)
+	if o.dialect == dbx.DialectSQLServer {
+		query = fmt.Sprintf("SELECT TOP (%d) id, topic, msg_key, payload, created_at FROM %s "+
+			"WHERE delivered_at IS NULL ORDER BY id", limit, o.table)
+	}
+
+	rows, err := o.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errx.Wrap("query pending messages", err)
+	}
+	defer rows.Close()
+
+	res, err := dbx.Scan(rows, func(m *Message) []interface{} {
+		return []interface{}{&m.ID, &m.Topic, &m.Key, &m.Payload, &m.CreatedAt}
+	})
+	return res, errx.Wrap("scan pending messages", err)
+}
+
+// delivered deletes delivered messages or marks them, if keep is true.
+func (o *Outbox) delivered(ctx context.Context, msgs []Message, keep bool) error {
+	style := o.dialect.PlaceholderStyle()
+
+	placeholders := make([]string, 0, len(msgs))
+	args := make([]any, 0, len(msgs))
+	for i, m := range msgs {
+		placeholders = append(placeholders, style.Placeholder(i+1))
+		args = append(args, m.ID)
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", o.table, strings.Join(placeholders, ", "))
+	if keep {
+		query = fmt.Sprintf("UPDATE %s SET delivered_at = CURRENT_TIMESTAMP WHERE id IN (%s)",
+			o.table, strings.Join(placeholders, ", "))
+	}
+
+	_, err := o.q.ExecContext(ctx, query, args...)
+	return errx.Wrap("mark messages delivered", err)
+}
@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"context"
+	stdsql "database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	o := NewSQL(db)
+	assert.Nil(t, o.Setup(ctx))
+
+	err = db.DoInTx(ctx, func(ctx context.Context) error {
+		assert.Nil(t, o.Write(ctx, "users", "1", []byte("created")))
+		assert.Nil(t, o.Write(ctx, "users", "1", []byte("updated")))
+		return nil
+	}, nil)
+	assert.Nil(t, err)
+
+	rollbackErr := errors.New("rollback")
+	err = db.DoInTx(ctx, func(ctx context.Context) error {
+		assert.Nil(t, o.Write(ctx, "users", "2", []byte("created")))
+		return rollbackErr
+	}, nil)
+	assert.ErrorIs(t, err, rollbackErr)
+
+	pub := &MemoryPublisher{}
+	n, err := o.RelayOnce(ctx, pub, WithBatchSize(1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = o.RelayOnce(ctx, pub)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	msgs := pub.Messages()
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, "created", string(msgs[0].Payload))
+	assert.Equal(t, "updated", string(msgs[1].Payload))
+
+	n, err = o.RelayOnce(ctx, pub)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestOutboxTableName(t *testing.T) {
+	db, err := sql.NewDB("sqlite3", []string{filepath.Join(t.TempDir(), "test.db")},
+		func(ctx context.Context, db *stdsql.DB) (bool, error) {
+			return true, nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	o := NewSQL(db, WithTable("user-events"))
+	assert.Nil(t, o.Setup(ctx))
+
+	err = db.DoInTx(ctx, func(ctx context.Context) error {
+		return o.Write(ctx, "users", "1", []byte("created"))
+	}, nil)
+	assert.Nil(t, err)
+
+	pub := &MemoryPublisher{}
+	n, err := o.RelayOnce(ctx, pub)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,165 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+)
+
+// Publisher publishes messages to broker. Messages must be published in passed order.
+// If Publish fails, the whole batch is published again, so delivery is at least once.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// MemoryPublisher is an in-memory Publisher for tests.
+type MemoryPublisher struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, msgs []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+// Messages returns published messages.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message{}, p.msgs...)
+}
+
+type relayConfig struct {
+	batchSize     int
+	pollInterval  time.Duration
+	keepDelivered bool
+	elector       *dbx.LeaderElector
+	onError       func(err error)
+}
+
+type RelayOption func(cfg *relayConfig)
+
+// WithBatchSize sets max number of messages, published at once.
+func WithBatchSize(size int) RelayOption {
+	return func(cfg *relayConfig) {
+		cfg.batchSize = size
+	}
+}
+
+// WithPollInterval sets delay between polls, when outbox is empty or publishing failed.
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(cfg *relayConfig) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithKeepDelivered marks delivered messages instead of deleting them.
+func WithKeepDelivered() RelayOption {
+	return func(cfg *relayConfig) {
+		cfg.keepDelivered = true
+	}
+}
+
+// WithLeaderElector runs relay only while leadership is held, so single relay publishes messages
+// at a time. Impls provide elector with NewLeaderElector method.
+func WithLeaderElector(elector *dbx.LeaderElector) RelayOption {
+	return func(cfg *relayConfig) {
+		cfg.elector = elector
+	}
+}
+
+// WithErrorHandler sets function, which is called with relay errors.
+func WithErrorHandler(onError func(err error)) RelayOption {
+	return func(cfg *relayConfig) {
+		cfg.onError = onError
+	}
+}
+
+// Relay publishes messages until ctx is done. Messages with the same key are published in commit order
+// (see Write). Run single relay per outbox table or guard it with WithLeaderElector,
+// otherwise messages may be published out of order.
+func (o *Outbox) Relay(ctx context.Context, pub Publisher, options ...RelayOption) error {
+	cfg := relayConfig{
+		batchSize:    DefaultBatchSize,
+		pollInterval: DefaultPollInterval,
+		onError:      func(err error) {},
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	if cfg.elector != nil {
+		return cfg.elector.Run(ctx, func(ctx context.Context) error {
+			return o.relay(ctx, pub, &cfg)
+		})
+	}
+
+	return o.relay(ctx, pub, &cfg)
+}
+
+func (o *Outbox) relay(ctx context.Context, pub Publisher, cfg *relayConfig) error {
+	for {
+		n, err := o.relayOnce(ctx, pub, cfg)
+		if err != nil && ctx.Err() == nil {
+			cfg.onError(err)
+		}
+
+		// Full batch means there may be more messages.
+		if err == nil && n == cfg.batchSize {
+			continue
+		}
+
+		timer := time.NewTimer(cfg.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RelayOnce publishes single batch of pending messages and returns its size.
+func (o *Outbox) RelayOnce(ctx context.Context, pub Publisher, options ...RelayOption) (int, error) {
+	cfg := relayConfig{
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	return o.relayOnce(ctx, pub, &cfg)
+}
+
+func (o *Outbox) relayOnce(ctx context.Context, pub Publisher, cfg *relayConfig) (int, error) {
+	msgs, err := o.pending(ctx, cfg.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, nil
+	}
+
+	if err := pub.Publish(ctx, msgs); err != nil {
+		return 0, errx.Wrap("publish", err)
+	}
+
+	if err := o.delivered(ctx, msgs, cfg.keepDelivered); err != nil {
+		return 0, err
+	}
+
+	return len(msgs), nil
+}
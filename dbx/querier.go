@@ -0,0 +1,24 @@
+package dbx
+
+import "context"
+
+// Result is a driver-agnostic result of query execution.
+type Result interface {
+	RowsAffected() (int64, error)
+}
+
+// QueryRows is a driver-agnostic result set. It must be closed after use.
+type QueryRows interface {
+	Rows
+	Columns() ([]string, error)
+	Close() error
+}
+
+// Querier is a driver-agnostic query API. Both impls provide adapters for it,
+// so shared code can be written once and run on any of them. Scan of QueryRowContext row returns error,
+// wrapping ErrNotFound, if there are no rows.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (QueryRows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) Row
+}
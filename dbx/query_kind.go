@@ -0,0 +1,77 @@
+package dbx
+
+import "strings"
+
+var readOnlyPrefixes = []string{"select", "values", "show", "table", "with"}
+
+var writeKeywords = []string{"insert", "update", "delete", "merge", "into", "returning"}
+
+// sideEffectFunctions are functions, which change state or take locks, even when they are called from SELECT.
+var sideEffectFunctions = []string{
+	"nextval", "setval", "currval", "lastval", "set_config", "pg_notify", "txid_current", "pg_current_xact_id",
+	"pg_cancel_backend", "pg_terminate_backend", "pg_reload_conf", "pg_switch_wal", "pg_create_restore_point",
+	"pg_logical_emit_message", "get_lock", "release_lock", "release_all_locks",
+}
+
+// sideEffectFunctionPrefixes are prefixes of function families with side effects: advisory locks
+// and large objects.
+var sideEffectFunctionPrefixes = []string{"pg_advisory_", "pg_try_advisory_", "lo_"}
+
+var lockClauses = []string{"for update", "for no key update", "for share", "for key share", "lock in share mode"}
+
+// IsReadOnlyQuery reports whether query looks like a read without locks and side effects, which can be
+// executed on standby. The check is conservative: any query, mentioning modifying keywords or known
+// side-effecting functions (nextval, advisory locks, etc.), is considered a write. Volatile user-defined
+// functions can not be detected, so such queries must be executed in transaction.
+func IsReadOnlyQuery(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var read bool
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(query, prefix) {
+			read = true
+			break
+		}
+	}
+	if !read {
+		return false
+	}
+
+	for _, clause := range lockClauses {
+		if strings.Contains(query, clause) {
+			return false
+		}
+	}
+
+	for _, field := range strings.FieldsFunc(query, func(r rune) bool {
+		return !(r == '_' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	}) {
+		if isWriteWord(field) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isWriteWord(word string) bool {
+	for _, keyword := range writeKeywords {
+		if word == keyword {
+			return true
+		}
+	}
+
+	for _, f := range sideEffectFunctions {
+		if word == f {
+			return true
+		}
+	}
+
+	for _, prefix := range sideEffectFunctionPrefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
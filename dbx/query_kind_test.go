@@ -0,0 +1,21 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	assert.True(t, IsReadOnlyQuery("  SELECT * FROM users WHERE id = $1"))
+	assert.True(t, IsReadOnlyQuery("with t as (select 1) select * from t"))
+	assert.True(t, IsReadOnlyQuery("select lower(name) from users"))
+	assert.False(t, IsReadOnlyQuery("select * from users for update"))
+	assert.False(t, IsReadOnlyQuery("with t as (delete from users returning *) select * from t"))
+	assert.False(t, IsReadOnlyQuery("select * into backup from users"))
+	assert.False(t, IsReadOnlyQuery("update users set name = $1"))
+	assert.False(t, IsReadOnlyQuery("insert into users (name) values ($1) returning id"))
+	assert.False(t, IsReadOnlyQuery("select nextval('users_id_seq')"))
+	assert.False(t, IsReadOnlyQuery("SELECT pg_try_advisory_lock($1)"))
+	assert.False(t, IsReadOnlyQuery("select pg_notify('events', $1)"))
+}
@@ -0,0 +1,326 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const (
+	DefaultRegistryMaxOpen     = 100
+	DefaultRegistryIdleTimeout = 10 * time.Minute
+)
+
+var (
+	// ErrRegistryFull is returned, when registry has max number of open clusters and all of them are in use.
+	ErrRegistryFull = errors.New("registry is full")
+	// ErrRegistryClosed is returned by closed registry.
+	ErrRegistryClosed = errors.New("registry is closed")
+)
+
+// DSNResolver looks up DSNs of tenant cluster nodes.
+type DSNResolver interface {
+	ResolveDSNs(ctx context.Context, tenantID string) ([]string, error)
+}
+
+// DSNResolverFunc is a function, which implements DSNResolver.
+type DSNResolverFunc func(ctx context.Context, tenantID string) ([]string, error)
+
+func (f DSNResolverFunc) ResolveDSNs(ctx context.Context, tenantID string) ([]string, error) {
+	return f(ctx, tenantID)
+}
+
+// RegistryOpener opens tenant DB over its cluster nodes. Impls provide openers with RegistryOpener function.
+type RegistryOpener[D any] func(ctx context.Context, tenantID string, dsns []string) (D, error)
+
+// Closer is a DB, which closes its cluster. Impl DBs close clusters with their ConnCloser.
+type Closer interface {
+	Close()
+}
+
+type registryConfig struct {
+	maxOpen     int
+	idleTimeout time.Duration
+}
+
+type RegistryOption func(cfg *registryConfig)
+
+// WithRegistryMaxOpen sets max number of open tenant clusters. When it is reached,
+// least recently used idle cluster is closed to open a new one.
+func WithRegistryMaxOpen(maxOpen int) RegistryOption {
+	return func(cfg *registryConfig) {
+		cfg.maxOpen = maxOpen
+	}
+}
+
+// WithRegistryIdleTimeout sets time, after which unused tenant cluster is closed. Zero disables idle eviction.
+func WithRegistryIdleTimeout(timeout time.Duration) RegistryOption {
+	return func(cfg *registryConfig) {
+		cfg.idleTimeout = timeout
+	}
+}
+
+// Registry maps tenant IDs to separate clusters for database-per-tenant deployments.
+// Clusters are opened lazily on first use and closed, when idle or evicted to keep
+// number of open clusters under the limit. Close function must be called when registry is not needed anymore.
+type Registry[D Closer] struct {
+	resolver DSNResolver
+	opener   RegistryOpener[D]
+	cfg      registryConfig
+
+	mu      sync.Mutex
+	entries map[string]*registryEntry[D]
+	closed  bool
+	stopper chan struct{}
+}
+
+type registryEntry[D Closer] struct {
+	ready    chan struct{}
+	db       D
+	err      error
+	refs     int
+	lastUsed time.Time
+}
+
+// NewRegistry returns registry, which opens tenant clusters with opener over DSNs, found by resolver.
+func NewRegistry[D Closer](resolver DSNResolver, opener RegistryOpener[D], options ...RegistryOption) *Registry[D] {
+	r := &Registry[D]{
+		resolver: resolver,
+		opener:   opener,
+		cfg: registryConfig{
+			maxOpen:     DefaultRegistryMaxOpen,
+			idleTimeout: DefaultRegistryIdleTimeout,
+		},
+		entries: make(map[string]*registryEntry[D]),
+		stopper: make(chan struct{}),
+	}
+
+	for _, opt := range options {
+		opt(&r.cfg)
+	}
+
+	if r.cfg.idleTimeout > 0 {
+		go r.backgroundEvict()
+	}
+
+	return r
+}
+
+// Get returns DB of tenant cluster, opening it, if needed. DB is pinned: it is not closed by idle timeout
+// or eviction until release function is called. Release function must be called, when DB is not needed anymore.
+func (r *Registry[D]) Get(ctx context.Context, tenantID string) (D, func(), error) {
+	e, err := r.acquire(ctx, tenantID)
+	if err != nil {
+		var d D
+		return d, nil, err
+	}
+
+	return e.db, sync.OnceFunc(func() { r.release(e) }), nil
+}
+
+// GetFromContext returns DB of tenant cluster for tenant, stored in ctx by ContextWithTenant. See Get.
+func (r *Registry[D]) GetFromContext(ctx context.Context) (D, func(), error) {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		var d D
+		return d, nil, ErrNoTenant
+	}
+
+	return r.Get(ctx, tenantID)
+}
+
+// Do executes f with DB of tenant cluster. Cluster is not closed until f returns.
+func (r *Registry[D]) Do(ctx context.Context, tenantID string, f func(ctx context.Context, db D) error) error {
+	e, err := r.acquire(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer r.release(e)
+
+	return f(ctx, e.db)
+}
+
+// DoFromContext executes f with DB of tenant cluster for tenant, stored in ctx by ContextWithTenant.
+func (r *Registry[D]) DoFromContext(ctx context.Context, f func(ctx context.Context, db D) error) error {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+
+	return r.Do(ctx, tenantID, f)
+}
+
+// Len returns number of open tenant clusters.
+func (r *Registry[D]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries)
+}
+
+// Close closes all tenant clusters and stops eviction.
+func (r *Registry[D]) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+
+	r.closed = true
+	close(r.stopper)
+	entries := r.entries
+	r.entries = make(map[string]*registryEntry[D])
+	r.mu.Unlock()
+
+	for _, e := range entries {
+		<-e.ready
+		if e.err == nil {
+			e.db.Close()
+		}
+	}
+}
+
+func (r *Registry[D]) acquire(ctx context.Context, tenantID string) (*registryEntry[D], error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrRegistryClosed
+	}
+
+	e, ok := r.entries[tenantID]
+	if ok {
+		e.refs++
+		r.mu.Unlock()
+	} else {
+		var evicted *registryEntry[D]
+		if len(r.entries) >= r.cfg.maxOpen {
+			if evicted = r.evictLRULocked(); evicted == nil {
+				r.mu.Unlock()
+				return nil, ErrRegistryFull
+			}
+		}
+
+		e = &registryEntry[D]{ready: make(chan struct{}), refs: 1}
+		r.entries[tenantID] = e
+		r.mu.Unlock()
+
+		if evicted != nil {
+			evicted.db.Close()
+		}
+
+		// Open is shared by all waiters, so it must not be canceled with ctx of the first one.
+		go r.open(context.WithoutCancel(ctx), tenantID, e)
+	}
+
+	select {
+	case <-e.ready:
+	case <-ctx.Done():
+		r.release(e)
+		return nil, ctx.Err()
+	}
+
+	if e.err != nil {
+		r.release(e)
+		return nil, e.err
+	}
+
+	return e, nil
+}
+
+func (r *Registry[D]) open(ctx context.Context, tenantID string, e *registryEntry[D]) {
+	defer close(e.ready)
+
+	dsns, err := r.resolver.ResolveDSNs(ctx, tenantID)
+	if err != nil {
+		e.err = errx.Wrap("resolve tenant dsns", err)
+	} else {
+		e.db, err = r.opener(ctx, tenantID, dsns)
+		e.err = errx.Wrap("open tenant db", err)
+	}
+
+	if e.err != nil {
+		// Failed entry is removed, so next call retries opening.
+		r.mu.Lock()
+		if r.entries[tenantID] == e {
+			delete(r.entries, tenantID)
+		}
+		r.mu.Unlock()
+	}
+}
+
+func (r *Registry[D]) release(e *registryEntry[D]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e.refs--
+	e.lastUsed = time.Now()
+}
+
+// evictLRULocked removes least recently used idle entry and returns it. Caller must close its DB.
+func (r *Registry[D]) evictLRULocked() *registryEntry[D] {
+	var (
+		lruID string
+		lru   *registryEntry[D]
+	)
+
+	for id, e := range r.entries {
+		if !r.idleLocked(e) {
+			continue
+		}
+
+		if lru == nil || e.lastUsed.Before(lru.lastUsed) {
+			lruID, lru = id, e
+		}
+	}
+
+	if lru != nil {
+		delete(r.entries, lruID)
+	}
+
+	return lru
+}
+
+// idleLocked reports whether entry is opened and is not in use.
+func (r *Registry[D]) idleLocked(e *registryEntry[D]) bool {
+	select {
+	case <-e.ready:
+		return e.refs == 0 && e.err == nil
+	default:
+		return false
+	}
+}
+
+// backgroundEvict periodically closes idle tenant clusters.
+func (r *Registry[D]) backgroundEvict() {
+	ticker := time.NewTicker(r.cfg.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stopper:
+			return
+		case <-ticker.C:
+			r.evictIdle()
+		}
+	}
+}
+
+func (r *Registry[D]) evictIdle() {
+	var evicted []*registryEntry[D]
+
+	r.mu.Lock()
+	for id, e := range r.entries {
+		if r.idleLocked(e) && time.Since(e.lastUsed) > r.cfg.idleTimeout {
+			delete(r.entries, id)
+			evicted = append(evicted, e)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, e := range evicted {
+		e.db.Close()
+	}
+}
@@ -0,0 +1,119 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type registryTestDB struct {
+	tenantID string
+	closed   atomic.Bool
+}
+
+func (db *registryTestDB) Close() {
+	db.closed.Store(true)
+}
+
+func TestRegistry(t *testing.T) {
+	var opened atomic.Int32
+	resolver := DSNResolverFunc(func(ctx context.Context, tenantID string) ([]string, error) {
+		if tenantID == "unknown" {
+			return nil, errors.New("unknown tenant")
+		}
+		return []string{tenantID}, nil
+	})
+	opener := func(ctx context.Context, tenantID string, dsns []string) (*registryTestDB, error) {
+		opened.Add(1)
+		return &registryTestDB{tenantID: tenantID}, nil
+	}
+
+	r := NewRegistry[*registryTestDB](resolver, opener, WithRegistryMaxOpen(2))
+	ctx := context.Background()
+
+	a, release, err := r.Get(ctx, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", a.tenantID)
+	release()
+
+	// Cluster is opened once.
+	again, release, err := r.GetFromContext(ContextWithTenant(ctx, "a"))
+	assert.Nil(t, err)
+	assert.Same(t, a, again)
+	assert.Equal(t, int32(1), opened.Load())
+	release()
+	release()
+
+	_, _, err = r.Get(ctx, "unknown")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, r.Len())
+
+	// Least recently used idle cluster is evicted, pinned one is kept.
+	err = r.Do(ctx, "b", func(ctx context.Context, b *registryTestDB) error {
+		c, release, err := r.Get(ctx, "c")
+		assert.Nil(t, err)
+		assert.True(t, a.closed.Load())
+		release()
+
+		d, releaseD, err := r.Get(ctx, "d")
+		assert.Nil(t, err)
+		assert.True(t, c.closed.Load())
+		assert.False(t, b.closed.Load())
+
+		// Clusters, pinned by Do and Get, are not evicted.
+		_, _, err = r.Get(ctx, "e")
+		assert.ErrorIs(t, err, ErrRegistryFull)
+		assert.False(t, d.closed.Load())
+
+		releaseD()
+		_, release, err = r.Get(ctx, "e")
+		assert.Nil(t, err)
+		assert.True(t, d.closed.Load())
+		release()
+		return nil
+	})
+	assert.Nil(t, err)
+
+	r.Close()
+	_, _, err = r.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrRegistryClosed)
+}
+
+func TestRegistryOpenDetached(t *testing.T) {
+	resolver := DSNResolverFunc(func(ctx context.Context, tenantID string) ([]string, error) {
+		return []string{tenantID}, nil
+	})
+	unblock := make(chan struct{})
+	opener := func(ctx context.Context, tenantID string, dsns []string) (*registryTestDB, error) {
+		<-unblock
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &registryTestDB{tenantID: tenantID}, nil
+	}
+
+	r := NewRegistry[*registryTestDB](resolver, opener)
+	t.Cleanup(r.Close)
+
+	// First caller gives up, while cluster is opening.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := r.Get(ctx, "a")
+		done <- err
+	}()
+	assert.Eventually(t, func() bool { return r.Len() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// Open is not canceled with it, so the next caller gets the cluster.
+	close(unblock)
+	a, release, err := r.Get(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", a.tenantID)
+	release()
+}
@@ -0,0 +1,114 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+const DefaultReadRetryMaxAttempts = 2
+
+// RetryPolicy describes, how failed queries are retried on another node.
+type RetryPolicy struct {
+	// MaxAttempts is a total number of attempts, including the first one. Values less than 2 disable retries.
+	MaxAttempts int
+	// Retryable reports whether error is worth retrying on another node. IsConnError is used, if nil.
+	Retryable func(err error) bool
+	// Idempotent reports whether query can be safely executed more than once. All queries are considered
+	// idempotent, if nil.
+	Idempotent func(query string) bool
+}
+
+// DefaultReadRetryPolicy retries reads, that failed with connection-level error, once on another node.
+func DefaultReadRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultReadRetryMaxAttempts,
+	}
+}
+
+// NoRetry disables retries.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 1,
+	}
+}
+
+func (p RetryPolicy) shouldRetry(query string, err error) bool {
+	if p.Idempotent != nil && !p.Idempotent(query) {
+		return false
+	}
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return IsConnError(err)
+}
+
+// IsConnError reports whether err looks like a connection-level error, e.g. node died or connection was reset.
+func IsConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// DoRead executes f on connection picked by ReadFromNodeStrategy and retries it on another alive node
+// according to ReadRetryPolicy.
+func (db *DB[T]) DoRead(ctx context.Context, query string, f func(conn T) error) error {
+	return db.DoRetry(ctx, db.ReadFromNodeStrategy, db.ReadRetryPolicy, query, f)
+}
+
+// DoRetry executes f on connection picked by strategy. If f fails with retryable error, it is executed again
+// on another alive node, that matches strategy criteria. Every retry is reported to Tracer.
+func (db *DB[T]) DoRetry(ctx context.Context, strategy GetNodeStragegy, policy RetryPolicy,
+	query string, f func(conn T) error) error {
+	node, err := db.GetNode(ctx, strategy)
+	if err != nil {
+		return errx.Wrap("wait for conn", err)
+	}
+
+	var tried []cluster.Node[T]
+	for attempt := 1; ; attempt++ {
+		err = f(node.DB())
+		if err == nil || attempt >= policy.MaxAttempts ||
+			ctx.Err() != nil || !policy.shouldRetry(query, err) {
+			return err
+		}
+
+		tried = append(tried, node)
+		next := db.Cluster.NodeExcept(strategy.Criteria, tried...)
+		if next == nil {
+			return err
+		}
+
+		db.Tracer.readRetry(ctx, attempt, node, next, err)
+
+		node = next
+	}
+}
@@ -0,0 +1,168 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoRetry(t *testing.T) {
+	db := newTestDB(t, "first", "second")
+
+	t.Run("retry on another node", func(t *testing.T) {
+		var traced []string
+		db := db.Copy()
+		db.Tracer.ReadRetry = func(ctx context.Context, attempt int, failed, next cluster.Node[string], err error) {
+			traced = append(traced, failed.Addr(), next.Addr())
+		}
+
+		var tried []string
+		err := db.DoRead(context.Background(), "select 1", func(conn string) error {
+			tried = append(tried, conn)
+			if len(tried) == 1 {
+				return driver.ErrBadConn
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Len(t, tried, 2)
+		assert.NotEqual(t, tried[0], tried[1])
+		assert.Equal(t, tried, traced)
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		queryErr := errors.New("syntax error")
+		var attempts int
+		err := db.DoRead(context.Background(), "select 1", func(conn string) error {
+			attempts++
+			return queryErr
+		})
+		assert.ErrorIs(t, err, queryErr)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("no more nodes", func(t *testing.T) {
+		var attempts int
+		err := db.DoRetry(context.Background(), db.ReadFromNodeStrategy,
+			RetryPolicy{MaxAttempts: 5}, "select 1", func(conn string) error {
+				attempts++
+				return driver.ErrBadConn
+			})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("not idempotent", func(t *testing.T) {
+		var attempts int
+		err := db.DoRetry(context.Background(), db.ReadFromNodeStrategy,
+			RetryPolicy{
+				MaxAttempts: 2,
+				Idempotent:  func(query string) bool { return false },
+			}, "select nextval('seq')", func(conn string) error {
+				attempts++
+				return driver.ErrBadConn
+			})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		assert.Equal(t, 1, attempts)
+	})
+}
+
+func newTestDB(t *testing.T, addrs ...string) *DB[string] {
+	t.Helper()
+
+	nodes := make([]cluster.Node[string], 0, len(addrs))
+	for _, addr := range addrs {
+		nodes = append(nodes, cluster.NewNode(addr, addr))
+	}
+
+	cl, err := cluster.NewCluster(nodes,
+		func(ctx context.Context, db string) (bool, error) {
+			return false, nil
+		},
+		func(db string) error {
+			return nil
+		})
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = cl.Close()
+	})
+
+	db := newDB[string]()
+	db.Cluster = cl
+
+	// Wait for initial nodes update.
+	_, err = cl.WaitForAlive(context.Background())
+	assert.Nil(t, err)
+
+	return db
+}
+
+type stateErr string
+
+func (e stateErr) Error() string {
+	return "sqlstate " + string(e)
+}
+
+func (e stateErr) SQLState() string {
+	return string(e)
+}
+
+// mysqlErr mirrors *mysql.MySQLError of go-sql-driver/mysql, which exposes error number as a field.
+type mysqlErr struct {
+	Number  uint16
+	Message string
+}
+
+func (e *mysqlErr) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+func TestRetryTx(t *testing.T) {
+	db := newDB[string]()
+	db.TxRetryPolicy.Backoff = nil
+
+	t.Run("serialization failure", func(t *testing.T) {
+		var traced []int
+		db := db.Copy()
+		db.Tracer.TxRetry = func(ctx context.Context, attempt int, err error) {
+			traced = append(traced, attempt)
+		}
+
+		var attempts int
+		err := db.RetryTx(context.Background(), func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("exec func in tx: %w", stateErr(SQLStateSerializationFailure))
+			}
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, []int{1, 2}, traced)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		var attempts int
+		err := db.RetryTx(context.Background(), func(ctx context.Context) error {
+			attempts++
+			return fmt.Errorf("exec: %w", &mysqlErr{Number: MySQLErrLockDeadlock, Message: "Deadlock found"})
+		})
+		assert.NotNil(t, err)
+		assert.Equal(t, DefaultTxRetryMaxAttempts, attempts)
+	})
+
+	t.Run("conn lost on commit", func(t *testing.T) {
+		var attempts int
+		err := db.RetryTx(context.Background(), func(ctx context.Context) error {
+			attempts++
+			return fmt.Errorf("commit: %w", &CommitError{Err: driver.ErrBadConn})
+		})
+		assert.ErrorIs(t, err, driver.ErrBadConn)
+		assert.Equal(t, 1, attempts)
+	})
+}
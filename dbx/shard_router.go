@@ -0,0 +1,146 @@
+package dbx
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+const DefaultHashRingReplicas = 128
+
+// ErrNoShard is returned, when shard key can not be routed to shard.
+var ErrNoShard = errors.New("no shard for key")
+
+// ShardRouter maps shard key to shard name.
+type ShardRouter interface {
+	Route(key string) (string, error)
+}
+
+// ShardRouterFunc is a function, which implements ShardRouter.
+type ShardRouterFunc func(key string) (string, error)
+
+func (f ShardRouterFunc) Route(key string) (string, error) {
+	return f(key)
+}
+
+// HashRing routes keys with consistent hashing, so adding shard moves only part of keys to it.
+type HashRing struct {
+	hashes []uint64
+	shards map[uint64]string
+}
+
+var _ ShardRouter = &HashRing{}
+
+// NewHashRing returns consistent hash ring over shards with replicas virtual nodes per shard.
+func NewHashRing(shards []string, replicas int) *HashRing {
+	r := &HashRing{
+		shards: make(map[uint64]string, len(shards)*replicas),
+	}
+
+	for _, shard := range shards {
+		for i := 0; i < replicas; i++ {
+			h := hashKey(shard + "#" + strconv.Itoa(i))
+			if _, ok := r.shards[h]; ok {
+				continue
+			}
+
+			r.shards[h] = shard
+			r.hashes = append(r.hashes, h)
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool {
+		return r.hashes[i] < r.hashes[j]
+	})
+
+	return r
+}
+
+// Route returns shard of first virtual node clockwise from key hash.
+func (r *HashRing) Route(key string) (string, error) {
+	if len(r.hashes) == 0 {
+		return "", ErrNoShard
+	}
+
+	h := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= h
+	})
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.shards[r.hashes[i]], nil
+}
+
+// ShardRange is a range of keys, starting with From inclusively, which belongs to Shard.
+type ShardRange struct {
+	From  string
+	Shard string
+}
+
+// RangeRouter routes keys by ranges, compared as strings. Numeric keys must be zero-padded
+// to the same length to keep their order.
+type RangeRouter struct {
+	ranges []ShardRange
+}
+
+var _ ShardRouter = &RangeRouter{}
+
+// NewRangeRouter returns router over ranges. Range ends, where next range starts.
+func NewRangeRouter(ranges ...ShardRange) *RangeRouter {
+	sorted := append([]ShardRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].From < sorted[j].From
+	})
+
+	return &RangeRouter{ranges: sorted}
+}
+
+func (r *RangeRouter) Route(key string) (string, error) {
+	i := sort.Search(len(r.ranges), func(i int) bool {
+		return r.ranges[i].From > key
+	})
+	if i == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoShard, key)
+	}
+
+	return r.ranges[i-1].Shard, nil
+}
+
+// LookupRouter routes keys by explicit map, e.g. tenants moved to dedicated shards.
+// Keys, missing in map, are routed by Fallback.
+type LookupRouter struct {
+	Shards   map[string]string
+	Fallback ShardRouter
+}
+
+var _ ShardRouter = &LookupRouter{}
+
+func (r *LookupRouter) Route(key string) (string, error) {
+	if shard, ok := r.Shards[key]; ok {
+		return shard, nil
+	}
+
+	if r.Fallback == nil {
+		return "", fmt.Errorf("%w: %s", ErrNoShard, key)
+	}
+
+	return r.Fallback.Route(key)
+}
+
+// hashKey returns fnv64a hash of key, mixed with murmur3 finalizer, so similar keys are spread over the ring.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
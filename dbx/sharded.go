@@ -0,0 +1,407 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// ShardedDB routes work by shard key across several clusters. Every shard is a DB over its own cluster,
+// so primary/standby selection, retries and transactions work as for single DB.
+//
+// Resharding is done in steps: new shards are added with AddShard and Reshard starts resharding to new router.
+// Keys, which new router moves to another shard, are still written to old shard, until they are marked moved
+// with MarkMoved, so writes are not lost, while rows are being copied. Key is moved by copying its rows
+// to new shard, marking it moved and deleting its rows from old shard. CompleteReshard drops old router,
+// when all keys are moved. By default moved keys are tracked in memory, so with several instances they must
+// share MovedKeys, see WithMovedKeys.
+type ShardedDB[D any] struct {
+	mu        sync.RWMutex
+	shards    map[string]D
+	router    ShardRouter
+	prev      ShardRouter // This is set during resharding
+	moved     MovedKeys   // Keys, moved during resharding
+	movedKeys MovedKeys   // Shared moved keys, if set with WithMovedKeys
+}
+
+// MovedKeys is a set of keys, moved during resharding. It is consulted on every routing of key during
+// resharding, so its methods must be fast. Shared implementations (e.g. backed by table or by source
+// of LookupRouter) let all instances see keys, marked moved by the resharding process.
+type MovedKeys interface {
+	// Moved reports, whether key is marked moved.
+	Moved(key string) (bool, error)
+	// MarkMoved marks keys moved.
+	MarkMoved(keys ...string) error
+}
+
+type ShardedOption func(o *shardedOptions)
+
+type shardedOptions struct {
+	movedKeys MovedKeys
+}
+
+// WithMovedKeys sets moved keys, shared by instances of sharded DB. It is used for every resharding,
+// so it must be emptied after CompleteReshard, before the next resharding starts.
+func WithMovedKeys(keys MovedKeys) ShardedOption {
+	return func(o *shardedOptions) {
+		o.movedKeys = keys
+	}
+}
+
+// NewShardedDB returns sharded DB over shards, named as router routes keys.
+func NewShardedDB[D any](shards map[string]D, router ShardRouter, options ...ShardedOption) *ShardedDB[D] {
+	var opts shardedOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	s := &ShardedDB[D]{
+		shards:    make(map[string]D, len(shards)),
+		router:    router,
+		movedKeys: opts.movedKeys,
+	}
+
+	for name, db := range shards {
+		s.shards[name] = db
+	}
+
+	return s
+}
+
+// Shard returns DB of shard, which key is written to. During resharding it is old shard, until key is marked moved.
+func (s *ShardedDB[D]) Shard(key string) (D, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var d D
+	name, err := s.ownerLocked(key)
+	if err != nil {
+		return d, err
+	}
+
+	return s.shardByNameLocked(name)
+}
+
+// Owns reports, whether key is written to shard. ScatterGatherShards callers use it to skip rows,
+// which are present on both shards during resharding.
+func (s *ShardedDB[D]) Owns(shard, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	name, err := s.ownerLocked(key)
+	return name == shard, err
+}
+
+// ShardByName returns DB of named shard, e.g. to move rows between shards during resharding.
+func (s *ShardedDB[D]) ShardByName(name string) (D, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	db, ok := s.shards[name]
+	return db, ok
+}
+
+// Shards returns names of all shards in sorted order.
+func (s *ShardedDB[D]) Shards() []string {
+	names, _ := s.snapshot()
+	return names
+}
+
+// Do executes f with DB of shard, which key is written to.
+func (s *ShardedDB[D]) Do(ctx context.Context, key string, f func(ctx context.Context, db D) error) error {
+	db, err := s.Shard(key)
+	if err != nil {
+		return err
+	}
+
+	return f(ctx, db)
+}
+
+// Read executes read f with DB of shard, which key is written to. During resharding, if key is not marked
+// moved yet and f fails with ErrNotFound or sql.ErrNoRows on old shard, it is executed with DB of new shard,
+// so rows are found, while they are being moved. Querier and single-row helpers of both impls return errors,
+// wrapping ErrNotFound.
+func (s *ShardedDB[D]) Read(ctx context.Context, key string, f func(ctx context.Context, db D) error) error {
+	s.mu.RLock()
+	from, to, moving, err := s.movingLocked(key)
+	if err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+
+	var fallback D
+	if moving {
+		fallback, err = s.shardByNameLocked(to)
+	} else {
+		from, err = s.ownerLocked(key)
+	}
+	if err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+
+	db, err := s.shardByNameLocked(from)
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	err = f(ctx, db)
+	if moving && (errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows)) {
+		return f(ctx, fallback)
+	}
+
+	return err
+}
+
+// Moving reports, whether key is being moved to another shard by current resharding and is not marked moved yet,
+// and returns both shards.
+func (s *ShardedDB[D]) Moving(key string) (from, to string, moving bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.movingLocked(key)
+}
+
+// AddShard adds shard, e.g. before resharding to it.
+func (s *ShardedDB[D]) AddShard(name string, db D) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.shards[name]; ok {
+		return fmt.Errorf("shard %s already exists", name)
+	}
+
+	s.shards[name] = db
+	return nil
+}
+
+// Reshard starts resharding to router. Keys are routed by old router, until they are marked moved.
+// Every instance must reshard to the same router, but keys must be moved and marked moved by single
+// writer process, and, unless MovedKeys are shared, other instances do not see them moved.
+func (s *ShardedDB[D]) Reshard(router ShardRouter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prev != nil {
+		return errors.New("resharding is in progress")
+	}
+
+	s.prev, s.router = s.router, router
+	s.moved = s.movedKeys
+	if s.moved == nil {
+		s.moved = memMovedKeys{}
+	}
+
+	return nil
+}
+
+// MarkMoved marks keys, which rows are copied to new shard, so they are written to and read from it.
+func (s *ShardedDB[D]) MarkMoved(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.prev == nil {
+		return errors.New("resharding is not in progress")
+	}
+
+	return errx.Wrap("mark moved", s.moved.MarkMoved(keys...))
+}
+
+// CompleteReshard completes resharding, when all keys are moved. Shards, which are no longer routed to,
+// are kept, so they must be removed with RemoveShard.
+func (s *ShardedDB[D]) CompleteReshard() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prev = nil
+	s.moved = nil
+}
+
+// RemoveShard removes shard and returns its DB, which caller must close.
+func (s *ShardedDB[D]) RemoveShard(name string) (D, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.shards[name]
+	delete(s.shards, name)
+	return db, ok
+}
+
+// Close closes DBs of all shards, which implement Closer.
+func (s *ShardedDB[D]) Close() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, db := range s.shards {
+		if closer, ok := any(db).(Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// snapshot returns names of all shards in sorted order with their DBs.
+func (s *ShardedDB[D]) snapshot() ([]string, []D) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.shards))
+	for name := range s.shards {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dbs := make([]D, len(names))
+	for i, name := range names {
+		dbs[i] = s.shards[name]
+	}
+
+	return names, dbs
+}
+
+func (s *ShardedDB[D]) shardByNameLocked(name string) (D, error) {
+	db, ok := s.shards[name]
+	if !ok {
+		var d D
+		return d, fmt.Errorf("%w: shard %s is not added", ErrNoShard, name)
+	}
+
+	return db, nil
+}
+
+// ownerLocked returns name of shard, which key is written to.
+func (s *ShardedDB[D]) ownerLocked(key string) (string, error) {
+	from, to, moving, err := s.movingLocked(key)
+	if err != nil {
+		return "", err
+	}
+
+	if moving {
+		return from, nil
+	}
+
+	to, err = s.router.Route(key)
+	return to, errx.Wrap("route shard key", err)
+}
+
+func (s *ShardedDB[D]) movingLocked(key string) (from, to string, moving bool, err error) {
+	if s.prev == nil {
+		return "", "", false, nil
+	}
+
+	moved, err := s.moved.Moved(key)
+	if err != nil {
+		return "", "", false, errx.Wrap("check moved key", err)
+	}
+	if moved {
+		return "", "", false, nil
+	}
+
+	if from, err = s.prev.Route(key); err != nil {
+		return "", "", false, errx.Wrap("route shard key", err)
+	}
+
+	if to, err = s.router.Route(key); err != nil {
+		return "", "", false, errx.Wrap("route shard key", err)
+	}
+
+	return from, to, from != to, nil
+}
+
+// memMovedKeys is a MovedKeys of single instance. It is guarded by ShardedDB mutex.
+type memMovedKeys map[string]struct{}
+
+func (m memMovedKeys) Moved(key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func (m memMovedKeys) MarkMoved(keys ...string) error {
+	for _, key := range keys {
+		m[key] = struct{}{}
+	}
+
+	return nil
+}
+
+// ShardResult is a result of f on single shard.
+type ShardResult[R any] struct {
+	Shard string
+	Value R
+	Err   error
+}
+
+// ScatterGather executes read f on all shards in parallel and merges their results in order of shard names.
+// Rows are kept only on shard, which their shard key, returned by key, is written to, so rows, which are present
+// on both shards during resharding, are not duplicated. Shard errors are joined, results of succeeded shards
+// are still returned.
+func ScatterGather[D, R any](ctx context.Context, s *ShardedDB[D], key func(row R) string,
+	f func(ctx context.Context, shard string, db D) ([]R, error)) ([]R, error) {
+	results := ScatterGatherShards(ctx, s, f)
+
+	var (
+		res  []R
+		errs []error
+	)
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", r.Shard, r.Err))
+			continue
+		}
+
+		for _, row := range r.Value {
+			owns, err := s.Owns(r.Shard, key(row))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("shard %s: %w", r.Shard, err))
+				break
+			}
+			if owns {
+				res = append(res, row)
+			}
+		}
+	}
+
+	return res, errors.Join(errs...)
+}
+
+// ScatterGatherShards executes f on all shards in parallel and returns result of every shard
+// in order of shard names. During resharding rows of keys, which are being moved, may be present on both shards,
+// so f must skip rows, which shard does not own, see ShardedDB.Owns.
+func ScatterGatherShards[D, R any](ctx context.Context, s *ShardedDB[D],
+	f func(ctx context.Context, shard string, db D) (R, error)) []ShardResult[R] {
+	names, dbs := s.snapshot()
+	results := make([]ShardResult[R], len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			value, err := f(ctx, name, dbs[i])
+			results[i] = ShardResult[R]{Shard: name, Value: value, Err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// DoInShardTx executes f in transaction on shard of key. Transaction is propagated through context,
+// as DoInTx does. Transactions across shards are not supported.
+func DoInShardTx[D TxManager[O], O any](ctx context.Context, s *ShardedDB[D], key string,
+	f func(ctx context.Context, db D) error, opts O) error {
+	db, err := s.Shard(key)
+	if err != nil {
+		return err
+	}
+
+	return db.DoInTx(ctx, func(ctx context.Context) error {
+		return f(ctx, db)
+	}, opts)
+}
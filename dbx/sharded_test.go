@@ -0,0 +1,185 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shardTestDB struct {
+	rows map[string]string
+}
+
+func (db *shardTestDB) get(key string) (string, error) {
+	v, ok := db.rows[key]
+	if !ok {
+		return "", NotFound(errors.New("no rows"))
+	}
+	return v, nil
+}
+
+func TestHashRing(t *testing.T) {
+	ring := NewHashRing([]string{"a", "b", "c"}, DefaultHashRingReplicas)
+	grown := NewHashRing([]string{"a", "b", "c", "d"}, DefaultHashRingReplicas)
+
+	counts := map[string]int{}
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint(i)
+		shard, err := ring.Route(key)
+		assert.Nil(t, err)
+		counts[shard]++
+
+		// Keys move only to added shard.
+		grownShard, err := grown.Route(key)
+		assert.Nil(t, err)
+		if grownShard != shard {
+			assert.Equal(t, "d", grownShard)
+			moved++
+		}
+	}
+	assert.Len(t, counts, 3)
+	assert.Less(t, moved, 500)
+
+	_, err := NewHashRing(nil, DefaultHashRingReplicas).Route("1")
+	assert.ErrorIs(t, err, ErrNoShard)
+}
+
+func TestRangeRouter(t *testing.T) {
+	r := NewRangeRouter(ShardRange{From: "m", Shard: "b"}, ShardRange{From: "a", Shard: "a"})
+
+	shard, err := r.Route("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", shard)
+
+	shard, err = r.Route("zoe")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", shard)
+
+	_, err = r.Route("1")
+	assert.ErrorIs(t, err, ErrNoShard)
+}
+
+func TestShardedDB(t *testing.T) {
+	router := NewRangeRouter(ShardRange{From: "a", Shard: "a"}, ShardRange{From: "m", Shard: "b"})
+	a := &shardTestDB{rows: map[string]string{"alice": "1", "bob": "2"}}
+	b := &shardTestDB{rows: map[string]string{"zoe": "3"}}
+	s := NewShardedDB(map[string]*shardTestDB{"a": a, "b": b}, router)
+	ctx := context.Background()
+
+	read := func(key string) (string, error) {
+		var v string
+		err := s.Read(ctx, key, func(ctx context.Context, db *shardTestDB) error {
+			var err error
+			v, err = db.get(key)
+			return err
+		})
+		return v, err
+	}
+
+	keys := func() []string {
+		keys, err := ScatterGather(ctx, s, func(key string) string { return key },
+			func(ctx context.Context, shard string, db *shardTestDB) ([]string, error) {
+				var res []string
+				for k := range db.rows {
+					res = append(res, k)
+				}
+				return res, nil
+			})
+		assert.Nil(t, err)
+		return keys
+	}
+	assert.ElementsMatch(t, []string{"alice", "bob", "zoe"}, keys())
+
+	// bob is moved to new shard c.
+	c := &shardTestDB{rows: map[string]string{}}
+	assert.Nil(t, s.AddShard("c", c))
+	assert.Nil(t, s.Reshard(&LookupRouter{Shards: map[string]string{"bob": "c"}, Fallback: router}))
+
+	from, to, moving, err := s.Moving("bob")
+	assert.Nil(t, err)
+	assert.True(t, moving)
+	assert.Equal(t, "a", from)
+	assert.Equal(t, "c", to)
+
+	// Key, which is not marked moved, is still written to old shard.
+	db, err := s.Shard("bob")
+	assert.Nil(t, err)
+	assert.Same(t, a, db)
+
+	// Row, which is copied, but not marked moved, is read from old shard and is not duplicated.
+	c.rows["bob"] = "2"
+	a.rows["bob"] = "4"
+	v, err := read("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, "4", v)
+	assert.ElementsMatch(t, []string{"alice", "bob", "zoe"}, keys())
+
+	owns, err := s.Owns("c", "bob")
+	assert.Nil(t, err)
+	assert.False(t, owns)
+
+	// Read falls back to new shard on sql.ErrNoRows too.
+	delete(a.rows, "bob")
+	err = s.Read(ctx, "bob", func(ctx context.Context, db *shardTestDB) error {
+		if db == a {
+			return sql.ErrNoRows
+		}
+		v, err = db.get("bob")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", v)
+
+	assert.Nil(t, s.MarkMoved("bob"))
+	_, _, moving, err = s.Moving("bob")
+	assert.Nil(t, err)
+	assert.False(t, moving)
+
+	db, err = s.Shard("bob")
+	assert.Nil(t, err)
+	assert.Same(t, c, db)
+
+	// Moved key is read from new shard only, stale row of old shard is skipped.
+	a.rows["bob"] = "4"
+	v, err = read("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", v)
+	assert.ElementsMatch(t, []string{"alice", "bob", "zoe"}, keys())
+
+	delete(a.rows, "bob")
+	s.CompleteReshard()
+	assert.NotNil(t, s.MarkMoved("bob"))
+
+	v, err = read("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", v)
+}
+
+func TestShardedDBSharedMovedKeys(t *testing.T) {
+	router := NewRangeRouter(ShardRange{From: "a", Shard: "a"})
+	a, c := &shardTestDB{}, &shardTestDB{}
+	shards := map[string]*shardTestDB{"a": a, "c": c}
+	moved := memMovedKeys{}
+
+	writer := NewShardedDB(shards, router, WithMovedKeys(moved))
+	reader := NewShardedDB(shards, router, WithMovedKeys(moved))
+
+	newRouter := &LookupRouter{Shards: map[string]string{"bob": "c"}, Fallback: router}
+	assert.Nil(t, writer.Reshard(newRouter))
+	assert.Nil(t, reader.Reshard(newRouter))
+
+	db, err := reader.Shard("bob")
+	assert.Nil(t, err)
+	assert.Same(t, a, db)
+
+	// Key, marked moved by writer, is written to new shard by other instances.
+	assert.Nil(t, writer.MarkMoved("bob"))
+	db, err = reader.Shard("bob")
+	assert.Nil(t, err)
+	assert.Same(t, c, db)
+}
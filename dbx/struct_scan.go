@@ -0,0 +1,186 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ValerySidorin/corex/errx"
+)
+
+// StructTag is a struct field tag, which holds column name. Fields tagged with "-" are skipped.
+// Untagged fields are mapped to snake_cased field name.
+const StructTag = "db"
+
+// StructScanMode defines, how columns, which are not mapped to any struct field, are handled.
+type StructScanMode int
+
+const (
+	// ScanStrict fails on unknown columns.
+	ScanStrict StructScanMode = iota
+	// ScanLenient ignores unknown columns.
+	ScanLenient
+)
+
+// ColumnRows is Rows, which know their column names.
+type ColumnRows interface {
+	Rows
+	Columns() ([]string, error)
+}
+
+// structPlan maps column names to field index paths of a single struct type.
+type structPlan struct {
+	fields map[string][]int
+}
+
+var structPlans sync.Map // reflect.Type -> *structPlan
+
+// ScanStructs scans rows into structs, mapping columns to fields by `db` tags.
+func ScanStructs[T any](rows ColumnRows, mode StructScanMode) ([]T, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, errx.Wrap("get columns", err)
+	}
+
+	pointers, err := StructPointers[T](columns, mode)
+	if err != nil {
+		return nil, errx.Wrap("get struct pointers", err)
+	}
+
+	return Scan(rows, pointers)
+}
+
+// StructPointers returns pointers function for Scan, which maps columns to fields of T by `db` tags.
+// Field plans are cached per type. Nil embedded struct pointers are allocated on demand.
+func StructPointers[T any](columns []string, mode StructScanMode) (func(*T) []interface{}, error) {
+	typ := reflect.TypeFor[T]()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", typ)
+	}
+
+	plan := getStructPlan(typ)
+
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := plan.fields[column]
+		if !ok && mode == ScanStrict {
+			return nil, fmt.Errorf("column %q is not mapped to any field of %s", column, typ)
+		}
+
+		indexes[i] = index
+	}
+
+	return func(elem *T) []interface{} {
+		v := reflect.ValueOf(elem).Elem()
+
+		res := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			if index == nil {
+				res[i] = new(interface{})
+				continue
+			}
+
+			res[i] = fieldByIndexAlloc(v, index).Addr().Interface()
+		}
+
+		return res
+	}, nil
+}
+
+func getStructPlan(typ reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(typ); ok {
+		return plan.(*structPlan)
+	}
+
+	plan := &structPlan{fields: make(map[string][]int)}
+	plan.add(typ, nil)
+
+	actual, _ := structPlans.LoadOrStore(typ, plan)
+	return actual.(*structPlan)
+}
+
+// add registers fields of typ. Fields of outer struct are registered before fields of embedded ones,
+// so they shadow embedded fields with the same column name.
+func (p *structPlan) add(typ reflect.Type, parent []int) {
+	var embedded []reflect.StructField
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, hasTag := field.Tag.Lookup(StructTag)
+		if tag == "-" {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && !hasTag && fieldType.Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+
+		if _, ok := p.fields[name]; !ok {
+			p.fields[name] = append(append([]int{}, parent...), i)
+		}
+	}
+
+	for _, field := range embedded {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			if !field.IsExported() {
+				// Unexported embedded pointer can not be allocated.
+				continue
+			}
+			fieldType = fieldType.Elem()
+		}
+
+		p.add(fieldType, append(append([]int{}, parent...), field.Index...))
+	}
+}
+
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, idx := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(idx)
+	}
+
+	return v
+}
+
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package dbx
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBase struct {
+	ID        int64
+	CreatedAt string
+}
+
+type testAudit struct {
+	UpdatedBy *string `db:"updated_by"`
+}
+
+type testUser struct {
+	testBase
+	*testAudit
+	Name     string  `db:"user_name"`
+	Email    *string `db:"email"`
+	Ignored  string  `db:"-"`
+	HTTPCode string
+}
+
+func TestStructPointers(t *testing.T) {
+	t.Run("mapping", func(t *testing.T) {
+		columns := []string{"id", "created_at", "user_name", "email", "http_code"}
+		pointers, err := StructPointers[testUser](columns, ScanStrict)
+		assert.Nil(t, err)
+
+		var u testUser
+		ptrs := pointers(&u)
+		assert.Len(t, ptrs, len(columns))
+		assert.Same(t, &u.ID, ptrs[0])
+		assert.Same(t, &u.CreatedAt, ptrs[1])
+		assert.Same(t, &u.Name, ptrs[2])
+		assert.Same(t, &u.Email, ptrs[3])
+		assert.Same(t, &u.HTTPCode, ptrs[4])
+	})
+
+	t.Run("unexported embedded pointer", func(t *testing.T) {
+		_, err := StructPointers[testUser]([]string{"updated_by"}, ScanStrict)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := StructPointers[testUser]([]string{"id", "unknown"}, ScanStrict)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		pointers, err := StructPointers[testUser]([]string{"id", "unknown"}, ScanLenient)
+		assert.Nil(t, err)
+
+		var u testUser
+		ptrs := pointers(&u)
+		assert.Same(t, &u.ID, ptrs[0])
+		assert.IsType(t, new(interface{}), ptrs[1])
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := StructPointers[int]([]string{"id"}, ScanStrict)
+		assert.NotNil(t, err)
+	})
+}
+
+type TestAuditInfo struct {
+	UpdatedBy string
+}
+
+type testDocument struct {
+	*TestAuditInfo
+	Title string
+}
+
+func TestStructPointersAllocEmbedded(t *testing.T) {
+	pointers, err := StructPointers[testDocument]([]string{"title", "updated_by"}, ScanStrict)
+	assert.Nil(t, err)
+
+	var d testDocument
+	ptrs := pointers(&d)
+	assert.NotNil(t, d.TestAuditInfo)
+	assert.Same(t, &d.TestAuditInfo.UpdatedBy, ptrs[1])
+}
@@ -0,0 +1,106 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoTenant is returned, when tenant scope requires tenant, but ctx carries none.
+var ErrNoTenant = errors.New("no tenant in context")
+
+// TenantScope applies per-tenant session settings for schema-per-tenant and row-level security
+// deployments. Tenant ID is taken from ctx, stored by ContextWithTenant. Supported only by Postgres.
+//
+// Settings are applied on every connection checkout and are reset (see ResetQuery), before connection
+// is returned to pool, so they never leak to statements, which do not apply tenant scope: of DB copies
+// without scope, advisory locks, listeners, etc. Connection, which can not be reset, is closed.
+// In transactions settings are applied with SET LOCAL semantics. database/sql impl runs single statements
+// in their own transactions, so their connections need no reset.
+type TenantScope struct {
+	SearchPath func(tenantID string) []string // Schemas of tenant search_path, which are quoted
+	Role       func(tenantID string) string   // Role to switch to, as SET ROLE does
+	Setting    string                         // Custom setting, which is set to tenant ID for RLS policies, e.g. app.tenant_id
+	Required   bool                           // Statements without tenant in ctx fail with ErrNoTenant
+}
+
+type tenantCtxKey struct{}
+
+// ContextWithTenant returns a copy of ctx, which carries tenant ID.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey{}, tenantID)
+}
+
+// TenantFromContext returns tenant ID, stored in ctx by ContextWithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	tenantID, ok := ctx.Value(tenantCtxKey{}).(string)
+	return tenantID, ok
+}
+
+// Query returns query with its args, which applies settings of tenant from ctx. If local is true,
+// settings are limited to current transaction, as SET LOCAL does.
+func (s *TenantScope) Query(ctx context.Context, local bool) (string, []any, error) {
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok && s.Required {
+		return "", nil, ErrNoTenant
+	}
+
+	query, args := s.query(tenantID, ok, local)
+	return query, args, nil
+}
+
+// ResetQuery returns query with its args, which restores session defaults of settings, changed by Query.
+func (s *TenantScope) ResetQuery() (string, []any) {
+	return s.query("", false, false)
+}
+
+func (s *TenantScope) query(tenantID string, ok, local bool) (string, []any) {
+	var (
+		exprs []string
+		args  []any
+	)
+
+	setConfig := func(name, value string) {
+		exprs = append(exprs, fmt.Sprintf("set_config(%s, %s, %t)", name, value, local))
+	}
+	arg := func(v any) string {
+		args = append(args, v)
+		return PlaceholderDollar.Placeholder(len(args))
+	}
+
+	if s.SearchPath != nil {
+		if ok {
+			schemas := s.SearchPath(tenantID)
+			quoted := make([]string, 0, len(schemas))
+			for _, schema := range schemas {
+				quoted = append(quoted, `"`+strings.ReplaceAll(schema, `"`, `""`)+`"`)
+			}
+			setConfig("'search_path'", arg(strings.Join(quoted, ", ")))
+		} else {
+			setConfig("'search_path'", "(SELECT reset_val FROM pg_settings WHERE name = 'search_path')")
+		}
+	}
+
+	if s.Role != nil {
+		if ok {
+			setConfig("'role'", arg(s.Role(tenantID)))
+		} else {
+			setConfig("'role'", "'none'")
+		}
+	}
+
+	if s.Setting != "" {
+		setConfig(arg(s.Setting), arg(tenantID))
+	}
+
+	if len(exprs) == 0 {
+		return "", nil
+	}
+
+	return "SELECT " + strings.Join(exprs, ", "), args
+}
@@ -0,0 +1,47 @@
+package dbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantScopeQuery(t *testing.T) {
+	scope := &TenantScope{
+		SearchPath: func(tenantID string) []string {
+			return []string{"tenant_" + tenantID, "public"}
+		},
+		Role: func(tenantID string) string {
+			return "tenant_" + tenantID
+		},
+		Setting: "app.tenant_id",
+	}
+
+	ctx := ContextWithTenant(context.Background(), `a"b`)
+	query, args, err := scope.Query(ctx, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT set_config('search_path', $1, true), set_config('role', $2, true), "+
+		"set_config($3, $4, true)", query)
+	assert.Equal(t, []any{`"tenant_a""b", "public"`, `tenant_a"b`, "app.tenant_id", `a"b`}, args)
+
+	// Defaults are restored without tenant.
+	query, args, err = scope.Query(context.Background(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT set_config('search_path', (SELECT reset_val FROM pg_settings WHERE name = 'search_path'), false), "+
+		"set_config('role', 'none', false), set_config($1, $2, false)", query)
+	assert.Equal(t, []any{"app.tenant_id", ""}, args)
+
+	scope.Required = true
+	_, _, err = scope.Query(context.Background(), false)
+	assert.ErrorIs(t, err, ErrNoTenant)
+
+	// Reset does not require tenant.
+	resetQuery, resetArgs := scope.ResetQuery()
+	assert.Equal(t, query, resetQuery)
+	assert.Equal(t, args, resetArgs)
+
+	query, _, err = (&TenantScope{}).Query(ctx, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "", query)
+}
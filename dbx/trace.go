@@ -0,0 +1,36 @@
+package dbx
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+)
+
+// Tracer is a set of hooks to run, when DB operations are retried or hedged on another node.
+// Any particular hook may be nil. Functions may be called concurrently from different goroutines.
+type Tracer[T any] struct {
+	// ReadRetry is called before failed read is retried on next node.
+	ReadRetry func(ctx context.Context, attempt int, failed, next cluster.Node[T], err error)
+	// TxRetry is called before failed transaction is retried.
+	TxRetry func(ctx context.Context, attempt int, err error)
+	// Hedge is called before read, which has not answered within hedge delay on slow node, is sent to next node.
+	Hedge func(ctx context.Context, slow, next cluster.Node[T])
+}
+
+func (t Tracer[T]) readRetry(ctx context.Context, attempt int, failed, next cluster.Node[T], err error) {
+	if t.ReadRetry != nil {
+		t.ReadRetry(ctx, attempt, failed, next, err)
+	}
+}
+
+func (t Tracer[T]) txRetry(ctx context.Context, attempt int, err error) {
+	if t.TxRetry != nil {
+		t.TxRetry(ctx, attempt, err)
+	}
+}
+
+func (t Tracer[T]) hedge(ctx context.Context, slow, next cluster.Node[T]) {
+	if t.Hedge != nil {
+		t.Hedge(ctx, slow, next)
+	}
+}
@@ -0,0 +1,51 @@
+package dbx
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx/cluster"
+)
+
+// TxManager executes functions in transaction, which is propagated to DB methods through context.
+// This allows to compose several repositories in one transaction without passing transaction around.
+type TxManager[TTxOptions any] interface {
+	DoInTx(ctx context.Context, f func(ctx context.Context) error, opts TTxOptions) error
+}
+
+type txCtxKey[T any] struct {
+	cluster *cluster.Cluster[T]
+}
+
+// ContextWithTx returns a copy of ctx, which carries transaction-bound tx of db cluster.
+func (db *DB[T]) ContextWithTx(ctx context.Context, tx any) context.Context {
+	return context.WithValue(ctx, txCtxKey[T]{cluster: db.Cluster}, tx)
+}
+
+// TxFromContext returns transaction-bound value, stored in ctx by ContextWithTx for the same cluster.
+func (db *DB[T]) TxFromContext(ctx context.Context) (any, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	tx := ctx.Value(txCtxKey[T]{cluster: db.Cluster})
+	return tx, tx != nil
+}
+
+type connCtxKey[T any] struct {
+	cluster *cluster.Cluster[T]
+}
+
+// ContextWithConn returns a copy of ctx, which carries pinned connection-bound conn of db cluster.
+func (db *DB[T]) ContextWithConn(ctx context.Context, conn any) context.Context {
+	return context.WithValue(ctx, connCtxKey[T]{cluster: db.Cluster}, conn)
+}
+
+// ConnFromContext returns connection-bound value, stored in ctx by ContextWithConn for the same cluster.
+func (db *DB[T]) ConnFromContext(ctx context.Context) (any, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	conn := ctx.Value(connCtxKey[T]{cluster: db.Cluster})
+	return conn, conn != nil
+}
@@ -0,0 +1,97 @@
+package dbx
+
+import (
+	"context"
+	"sync"
+)
+
+// TxHooks is a set of callbacks, which are called after transaction is finished.
+// Every nested (savepoint) scope has its own hooks, which are merged into parent ones, when savepoint is released.
+type TxHooks struct {
+	parent *TxHooks
+
+	mu         sync.Mutex
+	onCommit   []func(ctx context.Context)
+	onRollback []func(ctx context.Context, err error)
+}
+
+// Nested returns hooks of nested (savepoint) scope.
+func (h *TxHooks) Nested() *TxHooks {
+	return &TxHooks{parent: h}
+}
+
+// OnCommit registers f to be called after transaction is committed.
+func (h *TxHooks) OnCommit(f func(ctx context.Context)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onCommit = append(h.onCommit, f)
+}
+
+// OnRollback registers f to be called after transaction is rolled back.
+func (h *TxHooks) OnRollback(f func(ctx context.Context, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.onRollback = append(h.onRollback, f)
+}
+
+// Committed calls registered commit hooks in order of registration. Hooks are called at most once.
+// If scope is nested, its hooks are merged into parent ones instead, as savepoint release commits nothing.
+func (h *TxHooks) Committed(ctx context.Context) {
+	h.mu.Lock()
+	onCommit, onRollback := h.onCommit, h.onRollback
+	h.onCommit, h.onRollback = nil, nil
+	h.mu.Unlock()
+
+	if h.parent != nil {
+		h.parent.mu.Lock()
+		h.parent.onCommit = append(h.parent.onCommit, onCommit...)
+		h.parent.onRollback = append(h.parent.onRollback, onRollback...)
+		h.parent.mu.Unlock()
+		return
+	}
+
+	for _, f := range onCommit {
+		f(ctx)
+	}
+}
+
+// RolledBack calls registered rollback hooks in order of registration and drops commit hooks.
+// Hooks are called at most once. If scope is nested, hooks are called after rollback to savepoint.
+func (h *TxHooks) RolledBack(ctx context.Context, err error) {
+	h.mu.Lock()
+	hooks := h.onRollback
+	h.onCommit, h.onRollback = nil, nil
+	h.mu.Unlock()
+
+	for _, f := range hooks {
+		f(ctx, err)
+	}
+}
+
+// OnCommit registers f to be called after the outermost transaction is committed.
+// Hooks, registered in nested transaction, are attached to the outer one, when its savepoint is released,
+// and dropped, when it is rolled back.
+// If DB is not bound to transaction, f is called immediately.
+func (db *DB[T]) OnCommit(f func(ctx context.Context)) {
+	if db.TxHooks == nil {
+		f(db.Ctx)
+		return
+	}
+
+	db.TxHooks.OnCommit(f)
+}
+
+// OnRollback registers f to be called after transaction is rolled back with the error,
+// that caused rollback. The error is nil, if transaction function panicked.
+// Hooks, registered in nested transaction, are called after rollback to its savepoint, or attached
+// to the outer transaction, when its savepoint is released.
+// If DB is not bound to transaction, f is never called.
+func (db *DB[T]) OnRollback(f func(ctx context.Context, err error)) {
+	if db.TxHooks == nil {
+		return
+	}
+
+	db.TxHooks.OnRollback(f)
+}
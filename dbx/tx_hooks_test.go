@@ -0,0 +1,44 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxHooks(t *testing.T) {
+	ctx := context.Background()
+	errTx := errors.New("tx")
+
+	t.Run("released savepoint", func(t *testing.T) {
+		var events []string
+		hooks := &TxHooks{}
+		nested := hooks.Nested()
+		nested.OnCommit(func(ctx context.Context) { events = append(events, "commit") })
+		nested.OnRollback(func(ctx context.Context, err error) { events = append(events, "rollback") })
+
+		// Hooks of released savepoint follow the outer transaction.
+		nested.Committed(ctx)
+		assert.Empty(t, events)
+
+		hooks.RolledBack(ctx, errTx)
+		assert.Equal(t, []string{"rollback"}, events)
+	})
+
+	t.Run("rolled back savepoint", func(t *testing.T) {
+		var events []string
+		hooks := &TxHooks{}
+		nested := hooks.Nested()
+		nested.OnCommit(func(ctx context.Context) { events = append(events, "commit") })
+		nested.OnRollback(func(ctx context.Context, err error) { events = append(events, "rollback") })
+
+		// Hooks of rolled back savepoint are not attached to the outer transaction.
+		nested.RolledBack(ctx, errTx)
+		assert.Equal(t, []string{"rollback"}, events)
+
+		hooks.Committed(ctx)
+		assert.Equal(t, []string{"rollback"}, events)
+	})
+}
@@ -0,0 +1,181 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"reflect"
+	"time"
+)
+
+const (
+	DefaultTxRetryMaxAttempts = 3
+	DefaultTxRetryBaseBackoff = 10 * time.Millisecond
+	DefaultTxRetryMaxBackoff  = time.Second
+)
+
+// SQLSTATE and MySQL error codes, which mean that transaction was rolled back and can be safely retried.
+const (
+	SQLStateSerializationFailure = "40001"
+	SQLStateDeadlockDetected     = "40P01"
+	MySQLErrLockDeadlock         = 1213
+)
+
+// TxRetryPolicy describes, how failed transactions are retried.
+type TxRetryPolicy struct {
+	// MaxAttempts is a total number of attempts, including the first one. Values less than 2 disable retries.
+	MaxAttempts int
+	// Backoff returns delay before next attempt. There is no delay, if nil.
+	Backoff func(attempt int) time.Duration
+	// Retryable reports whether transaction can be retried after err. IsTxRetryable is used, if nil.
+	Retryable func(err error) bool
+	// OnRetry is called before every retry. May be nil.
+	OnRetry func(ctx context.Context, attempt int, err error)
+}
+
+// DefaultTxRetryPolicy retries serialization failures, deadlocks and connection loss before commit
+// with exponential backoff.
+func DefaultTxRetryPolicy() TxRetryPolicy {
+	return TxRetryPolicy{
+		MaxAttempts: DefaultTxRetryMaxAttempts,
+		Backoff:     ExponentialBackoff(DefaultTxRetryBaseBackoff, DefaultTxRetryMaxBackoff),
+	}
+}
+
+func (p TxRetryPolicy) shouldRetry(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return IsTxRetryable(err)
+}
+
+// ExponentialBackoff returns backoff function, which doubles delay on every attempt
+// up to max and adds random jitter.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if d <= 0 {
+			return 0
+		}
+
+		return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+}
+
+// CommitError is returned, when transaction commit fails. If connection was lost during commit,
+// transaction outcome is unknown, so such transactions are not retried.
+type CommitError struct {
+	Err error
+}
+
+func (e *CommitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// IsTxRetryable reports whether transaction, which failed with err, can be safely retried from the start.
+func IsTxRetryable(err error) bool {
+	if IsSerializationFailure(err) {
+		return true
+	}
+
+	var commitErr *CommitError
+	if errors.As(err, &commitErr) {
+		return false
+	}
+
+	return IsConnError(err)
+}
+
+// IsSerializationFailure reports whether err is a serialization failure or deadlock. Driver error must expose
+// SQLSTATE with SQLState() method or MySQL error number with Number field (*mysql.MySQLError does) or method.
+func IsSerializationFailure(err error) bool {
+	switch SQLState(err) {
+	case SQLStateSerializationFailure, SQLStateDeadlockDetected:
+		return true
+	}
+
+	number, ok := errorNumber(err)
+	return ok && number == MySQLErrLockDeadlock
+}
+
+// SQLState returns SQLSTATE code of err, if driver error exposes it with SQLState() method (pgconn.PgError does).
+func SQLState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+
+	return ""
+}
+
+// errorNumber returns vendor error number of the first error in err tree, which exposes it with Number() method
+// or uint16 Number field.
+func errorNumber(err error) (uint16, bool) {
+	if err == nil {
+		return 0, false
+	}
+
+	if numberErr, ok := err.(interface{ Number() uint16 }); ok {
+		return numberErr.Number(), true
+	}
+
+	if v := reflect.Indirect(reflect.ValueOf(err)); v.Kind() == reflect.Struct {
+		if f := v.FieldByName("Number"); f.IsValid() && f.Kind() == reflect.Uint16 {
+			return uint16(f.Uint()), true
+		}
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return errorNumber(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if number, ok := errorNumber(err); ok {
+				return number, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// RetryTx executes f until it succeeds, fails with non retryable error or attempts are exhausted
+// according to TxRetryPolicy. f is expected to run the whole transaction, so node is picked again on every attempt.
+func (db *DB[T]) RetryTx(ctx context.Context, f func(ctx context.Context) error) error {
+	policy := db.TxRetryPolicy
+
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt >= policy.MaxAttempts ||
+			ctx.Err() != nil || !policy.shouldRetry(err) {
+			return err
+		}
+
+		db.Tracer.txRetry(ctx, attempt, err)
+
+		if policy.OnRetry != nil {
+			policy.OnRetry(ctx, attempt, err)
+		}
+
+		if policy.Backoff != nil {
+			timer := time.NewTimer(policy.Backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	stdsql "database/sql"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/impl/sql"
+	"github.com/ValerySidorin/corex/errx"
+)
+
+/*
+This is a service, that composes several repos in one transaction. Repos don't know about
+transaction at all: it is propagated through context. Usage:
+	var db *sql.DB // initialized as nil to simplify example
+	s := NewService(db, NewUsers(db), NewAudit(db))
+
+	err := s.Register(ctx, "john")
+	if err != nil {
+		return errx.Wrap("register", err)
+	}
+*/
+
+type Service struct {
+	txm   dbx.TxManager[*stdsql.TxOptions]
+	users *Users
+	audit *Audit
+}
+
+func NewService(txm dbx.TxManager[*stdsql.TxOptions], users *Users, audit *Audit) *Service {
+	return &Service{
+		txm:   txm,
+		users: users,
+		audit: audit,
+	}
+}
+
+func (s *Service) Register(ctx context.Context, name string) error {
+	err := s.txm.DoInTx(ctx, func(ctx context.Context) error {
+		if err := s.users.Create(ctx, name); err != nil {
+			return errx.Wrap("create user", err)
+		}
+
+		return errx.Wrap("log", s.audit.Log(ctx, "user registered"))
+	}, nil)
+
+	return errx.Wrap("do in tx", err)
+}
+
+type Users struct {
+	db *sql.DB
+}
+
+func NewUsers(db *sql.DB) *Users {
+	return &Users{db: db}
+}
+
+func (u *Users) Create(ctx context.Context, name string) error {
+	_, err := u.db.ExecContext(ctx, "insert into users(name) values(?)", name)
+	return errx.Wrap("exec context", err)
+}
+
+type Audit struct {
+	db *sql.DB
+}
+
+func NewAudit(db *sql.DB) *Audit {
+	return &Audit{db: db}
+}
+
+func (a *Audit) Log(ctx context.Context, msg string) error {
+	_, err := a.db.ExecContext(ctx, "insert into audit(msg) values(?)", msg)
+	return errx.Wrap("exec context", err)
+}
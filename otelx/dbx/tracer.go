@@ -0,0 +1,36 @@
+package dbx
+
+import (
+	"context"
+
+	"github.com/ValerySidorin/corex/dbx"
+	"github.com/ValerySidorin/corex/dbx/cluster"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns dbx tracer, which reports retries and hedges as events of the span from ctx.
+func Tracer[T any]() dbx.Tracer[T] {
+	return dbx.Tracer[T]{
+		ReadRetry: func(ctx context.Context, attempt int, failed, next cluster.Node[T], err error) {
+			trace.SpanFromContext(ctx).AddEvent("dbx.retry", trace.WithAttributes(
+				attribute.Int("dbx.retry.attempt", attempt),
+				attribute.String("dbx.retry.failed_node", failed.Addr()),
+				attribute.String("dbx.retry.next_node", next.Addr()),
+				attribute.String("dbx.retry.error", err.Error()),
+			))
+		},
+		TxRetry: func(ctx context.Context, attempt int, err error) {
+			trace.SpanFromContext(ctx).AddEvent("dbx.tx_retry", trace.WithAttributes(
+				attribute.Int("dbx.tx_retry.attempt", attempt),
+				attribute.String("dbx.tx_retry.error", err.Error()),
+			))
+		},
+		Hedge: func(ctx context.Context, slow, next cluster.Node[T]) {
+			trace.SpanFromContext(ctx).AddEvent("dbx.hedge", trace.WithAttributes(
+				attribute.String("dbx.hedge.slow_node", slow.Addr()),
+				attribute.String("dbx.hedge.next_node", next.Addr()),
+			))
+		},
+	}
+}